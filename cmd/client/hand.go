@@ -0,0 +1,371 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/EdoardoLaGreca/dubito/assets"
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
+)
+
+// size of a card in the hand
+const cardWidth, cardHeight = 40.0, 80.0
+
+// order of the hand when a game starts, it is saved with the settings
+var handOrder cardutils.SortOrder = cardutils.ByRank
+
+// background of the cards: selected, and unselected in even and odd groups of the same rank
+var colorSelected = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+var colorGroupEven = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+var colorGroupOdd = color.RGBA{R: 90, G: 90, B: 90, A: 255}
+
+// a card of the hand, which can be tapped to select it and dragged to move it
+type cardWidget struct {
+	widget.BaseWidget
+
+	card       cardutils.Card
+	background *canvas.Rectangle
+	image      *canvas.Image
+
+	onTapped  func()
+	onDropped func(dx, dy float32) // called with how far the card was dragged
+	dx, dy    float32
+}
+
+func newCardWidget(card cardutils.Card, bg color.Color, onTapped func(), onDropped func(dx, dy float32)) (*cardWidget, error) {
+	img, err := assets.GetCardAsset(card)
+	if err != nil {
+		return nil, err
+	}
+
+	cw := &cardWidget{
+		card:       card,
+		background: canvas.NewRectangle(bg),
+		image:      canvas.NewImageFromImage(img),
+		onTapped:   onTapped,
+		onDropped:  onDropped,
+	}
+	cw.image.FillMode = canvas.ImageFillContain
+	cw.ExtendBaseWidget(cw)
+
+	return cw, nil
+}
+
+func (cw *cardWidget) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(container.NewMax(cw.background, cw.image))
+}
+
+func (cw *cardWidget) Tapped(*fyne.PointEvent) {
+	cw.onTapped()
+}
+
+func (cw *cardWidget) Dragged(ev *fyne.DragEvent) {
+	cw.dx += ev.Dragged.DX
+	cw.dy += ev.Dragged.DY
+
+	// the card follows the pointer until it is dropped
+	cw.Move(cw.Position().Add(ev.Dragged))
+}
+
+func (cw *cardWidget) DragEnd() {
+	dx, dy := cw.dx, cw.dy
+	cw.dx, cw.dy = 0, 0
+	cw.onDropped(dx, dy)
+}
+
+// the hand of the player in the game: the cards, their order and the selection
+// the cards are changed both by the buttons and by the goroutine which follows the game, hence the mutex
+type handView struct {
+	w     fyne.Window
+	mutex sync.Mutex
+
+	cards     cardutils.Hand
+	order     []cardutils.Card // the cards in the order they are shown, changed by sorting and by dragging
+	selected  cardutils.Hand   // at most 4 cards, to be placed
+	claimRank cardutils.Rank   // rank of the cards which the player has to claim in their turn
+	freeClaim bool             // true if the player starts the pile, so they can claim any rank
+
+	cardsCont   *fyne.Container // the cards
+	ranksCont   *fyne.Container // a button for each rank, which selects all the cards of that rank
+	lblSelected *widget.Label
+}
+
+func newHandView(w fyne.Window, cards cardutils.Hand) *handView {
+	hv := &handView{
+		w:           w,
+		cards:       cards,
+		order:       cards.Sort(handOrder),
+		claimRank:   cardutils.Ace,
+		cardsCont:   container.New(layout.NewGridWrapLayout(fyne.NewSize(cardWidth, cardHeight))),
+		ranksCont:   container.NewHBox(),
+		lblSelected: widget.NewLabel(i18n.T("game.noneSelected")),
+	}
+
+	hv.render()
+
+	return hv
+}
+
+// return the container with the sorting buttons, the rank buttons and the cards
+func (hv *handView) container() *fyne.Container {
+	btnByRank := widget.NewButton(i18n.T("hand.byRank"), func() {
+		hv.sort(cardutils.ByRank)
+	})
+
+	btnBySuit := widget.NewButton(i18n.T("hand.bySuit"), func() {
+		hv.sort(cardutils.BySuit)
+	})
+
+	sortCont := container.NewHBox(widget.NewLabel(i18n.T("hand.sort")), btnByRank, btnBySuit)
+
+	lblSelectAll := widget.NewLabel(i18n.T("hand.selectAll"))
+	ranksCont := container.NewBorder(nil, nil, lblSelectAll, nil, container.NewHScroll(hv.ranksCont))
+
+	return container.New(layout.NewVBoxLayout(), sortCont, ranksCont, hv.cardsCont)
+}
+
+// sort the hand, the order is remembered for the next games
+func (hv *handView) sort(order cardutils.SortOrder) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	handOrder = order
+	hv.order = hv.cards.Sort(order)
+	hv.render()
+}
+
+// replace the cards of the hand
+func (hv *handView) setCards(cards cardutils.Hand) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	hv.replaceCards(cards)
+}
+
+// add cards to the hand, such as the ones on the table after a wrong doubt
+func (hv *handView) addCards(cards ...cardutils.Card) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	newCards := hv.cards.Clone()
+	newCards.Add(cards...)
+	hv.replaceCards(newCards)
+}
+
+// remove the placed cards from the hand, which also clears the selection
+func (hv *handView) removeSelected() {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	newCards := hv.cards.Clone()
+	newCards.Remove(hv.selected.Cards()...)
+	hv.replaceCards(newCards)
+}
+
+// replace the cards of the hand, the cards still held keep their place and the new ones go at the end
+// hv.mutex must be held by the caller
+func (hv *handView) replaceCards(cards cardutils.Hand) {
+	remaining := cards.Clone()
+	order := make([]cardutils.Card, 0, cards.Len())
+	for _, c := range hv.order {
+		if remaining.Remove(c) {
+			order = append(order, c)
+		}
+	}
+	order = append(order, remaining.Sort(handOrder)...)
+
+	// the selected cards which are not held anymore are deselected
+	for _, c := range hv.selected.Cards() {
+		if !cards.Contains(c) {
+			hv.selected.Remove(c)
+		}
+	}
+
+	hv.cards = cards
+	hv.order = order
+	hv.render()
+}
+
+// return the selected cards in the order they are shown
+func (hv *handView) selection() []cardutils.Card {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	cards := make([]cardutils.Card, 0, hv.selected.Len())
+	for _, c := range hv.order {
+		if hv.selected.Contains(c) {
+			cards = append(cards, c)
+		}
+	}
+
+	return cards
+}
+
+// set the rank which the player has to claim when they place cards, free is true if they can claim any rank
+func (hv *handView) setClaim(r cardutils.Rank, free bool) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	hv.freeClaim = free
+	if hv.claimRank != r {
+		hv.claimRank = r
+		hv.updateLabel()
+	}
+}
+
+// return the rank which the player has to claim and whether they can claim any rank instead
+func (hv *handView) claim() (cardutils.Rank, bool) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	return hv.claimRank, hv.freeClaim
+}
+
+// select or deselect a card
+func (hv *handView) toggle(c cardutils.Card) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	if hv.selected.Contains(c) {
+		hv.selected.Remove(c)
+	} else if hv.selected.Len() < 4 {
+		hv.selected.Add(c)
+	} else {
+		dialog.ShowError(errors.New(i18n.T("game.tooManySelected")), hv.w)
+		return
+	}
+
+	hv.render()
+}
+
+// select all the cards of a rank, in place of the selected ones
+func (hv *handView) selectRank(r cardutils.Rank) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	cards := hv.cards.GroupByRank()[r]
+	if len(cards) > 4 {
+		cards = cards[:4]
+	}
+
+	hv.selected = cardutils.NewHand(cards...)
+	hv.render()
+}
+
+// move a card which was dragged by dx and dy
+func (hv *handView) drop(c cardutils.Card, dx, dy float32) {
+	hv.mutex.Lock()
+	defer hv.mutex.Unlock()
+
+	from := -1
+	for i, oc := range hv.order {
+		if oc == c {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		hv.render()
+		return
+	}
+
+	// the cards wrap in rows, so moving a row down moves the card by a row of cards
+	perRow := int(hv.cardsCont.Size().Width / cardWidth)
+	if perRow < 1 {
+		perRow = 1
+	}
+	to := from + int(math.Round(float64(dx/cardWidth))) + int(math.Round(float64(dy/cardHeight)))*perRow
+	to = max(0, min(to, len(hv.order)-1))
+
+	hv.order = append(hv.order[:from], hv.order[from+1:]...)
+	hv.order = append(hv.order[:to], append([]cardutils.Card{c}, hv.order[to:]...)...)
+	hv.render()
+}
+
+// show the cards, the rank buttons and the selection, hv.mutex must be held by the caller
+func (hv *handView) render() {
+	objects := make([]fyne.CanvasObject, 0, len(hv.order))
+
+	// adjacent cards of the same rank form a group, the groups alternate their background
+	group := 0
+	for i, c := range hv.order {
+		if i > 0 && c.Rank != hv.order[i-1].Rank {
+			group++
+		}
+
+		var bg color.Color = colorGroupEven
+		if hv.selected.Contains(c) {
+			bg = colorSelected
+		} else if group%2 == 1 {
+			bg = colorGroupOdd
+		}
+
+		// copy the card so that each function literal has its own
+		card := c
+		cw, err := newCardWidget(card, bg, func() {
+			hv.toggle(card)
+		}, func(dx, dy float32) {
+			hv.drop(card, dx, dy)
+		})
+		if err != nil {
+			dialog.ShowError(err, hv.w)
+			break
+		}
+
+		objects = append(objects, cw)
+	}
+
+	hv.cardsCont.Objects = objects
+	hv.cardsCont.Refresh()
+
+	counts := hv.cards.CountByRank()
+	buttons := make([]fyne.CanvasObject, 0, len(counts))
+	for r := cardutils.Ace; r <= cardutils.King; r++ {
+		if counts[r] == 0 {
+			continue
+		}
+
+		rank := r
+		buttons = append(buttons, widget.NewButton(i18n.T("hand.rank", cardutils.LocalRankName(r, i18n.CurrentLang()), counts[r]), func() {
+			hv.selectRank(rank)
+		}))
+	}
+
+	hv.ranksCont.Objects = buttons
+	hv.ranksCont.Refresh()
+
+	hv.updateLabel()
+}
+
+// show the selected cards, the claim they make and how much of it is true, hv.mutex must be held by the caller
+func (hv *handView) updateLabel() {
+	if hv.selected.Len() == 0 {
+		hv.lblSelected.SetText(i18n.T("game.noneSelected"))
+		return
+	}
+
+	selected := hv.selected.Sort(cardutils.ByRank)
+	text := i18n.T("game.selected", len(selected), strings.Join(cardutils.LocalCardNames(selected, i18n.CurrentLang()), ", "))
+
+	bluffed := 0
+	for _, c := range selected {
+		if c.Rank != hv.claimRank {
+			bluffed++
+		}
+	}
+
+	held := hv.cards.CountByRank()[hv.claimRank]
+	text += "\n" + i18n.T("hand.claim", len(selected), cardutils.LocalRankName(hv.claimRank, i18n.CurrentLang()), bluffed, held)
+
+	hv.lblSelected.SetText(text)
+}
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
 )
 
 var username string = "Player"
 
+// password of the account, empty to play as a guest
+var password string
+
 func main() {
+	i18n.SetLang(i18n.DetectLang())
+
+	// the saved language, if any, replaces the one of the system
+	loadErr := loadSettings()
+
 	a := app.New()
 	w := a.NewWindow("Dubito")
 	w.Resize(fyne.NewSize(450, 200))
@@ -15,5 +28,13 @@ func main() {
 	menuContainer := getMenuContainer(w)
 
 	w.SetContent(menuContainer)
+	if loadErr != nil {
+		dialog.ShowError(loadErr, w)
+	}
 	w.ShowAndRun()
+
+	err := saveSettings()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to save the settings: "+err.Error())
+	}
 }
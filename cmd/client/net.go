@@ -1,14 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
 	"github.com/EdoardoLaGreca/dubito/internal/netutils"
 )
 
@@ -24,6 +28,86 @@ type update struct {
 	playerTurn  bool           // not relevant if gameOver = true
 	cardsAmount int            // not relevant if gameOver = true
 	cardRank    cardutils.Rank // not relevant if gameOver = true
+	placement   int            // 0 if the player did not finish yet
+	shutdownIn  int            // seconds before the server ends the game to shut down, -1 if it is not shutting down
+}
+
+// response to "get room" request
+type roomState struct {
+	id         string
+	inviteCode string // empty if the room is public
+	minPlayers int
+	maxPlayers int
+	variant    string // "classic" or "placement"
+	turnTime   int    // seconds, 0 if there is no limit
+	ranked     bool
+	started    bool
+	players    []string // the first one is the host
+	ready      []bool   // ready[i] is true if players[i] is ready
+}
+
+// a line of the "get rooms" response
+type roomInfo struct {
+	id         string
+	host       string // empty if the room is empty
+	joined     int
+	maxPlayers int
+	started    bool
+	ranked     bool
+}
+
+// a line of the standings
+type standing struct {
+	placement int
+	name      string
+}
+
+// statistics of a player, the leaderboard only contains name, gamesPlayed and gamesWon
+type playerStats struct {
+	name            string
+	gamesPlayed     int
+	gamesWon        int
+	bluffsAttempted int
+	bluffsCaught    int
+	doubtsCalled    int
+	doubtsRight     int
+	avgCardsHeld    float64
+}
+
+type chatMessage struct {
+	id   int
+	from string // "*" if the message was sent by the server
+	text string
+}
+
+// response to "get table" request
+type tableState struct {
+	pile    int      // cards on the table
+	players []string // joined players
+	held    []int    // held[i] is the number of cards held by players[i]
+	events  []tableEvent
+}
+
+// a play or a doubt of the current game
+type tableEvent struct {
+	doubt  bool
+	player string // player who placed the cards or who doubted
+
+	// plays only
+	count int
+	claim cardutils.Rank
+
+	// doubts only
+	right   bool
+	doubted string // player who placed the doubted cards
+	taken   int    // cards taken by whoever was wrong
+}
+
+// rating of a player, not relevant if rated = false
+type rating struct {
+	rated  bool
+	rating int
+	change int // change caused by the last ranked game
 }
 
 var serverAddress string = "localhost"
@@ -35,8 +119,22 @@ var recvChan chan netResponse = make(chan netResponse) // receive messages
 var closeChan chan struct{} = make(chan struct{})      // the connection closed
 var stopCheckChan chan struct{} = make(chan struct{})  // stop receiving for messages
 
+// closed once the current connection can no longer receive messages
+var connDone chan struct{}
+
 var netMutex sync.Mutex
 
+// the server tells the interval and the timeout of the heartbeats in response to "ping", these are used until then
+var heartbeatInterval time.Duration = 5 * time.Second
+var heartbeatTimeout time.Duration = 20 * time.Second
+
+// round-trip time of the last heartbeat and when its response arrived
+var lastRTT time.Duration
+var lastPong time.Time
+
+// mutex for the heartbeat variables
+var heartbeatMutex sync.Mutex
+
 // do not use openConn if you call initConn
 func openConn(addr string, port uint16) (net.Conn, error) {
 	conn, err := net.Dial("tcp", addr+":"+strconv.Itoa(int(port)))
@@ -51,16 +149,55 @@ func initConn() error {
 
 	conn = c
 
+	heartbeatMutex.Lock()
+	lastRTT = 0
+	lastPong = time.Time{}
+	heartbeatMutex.Unlock()
+
+	done := make(chan struct{})
+	connDone = done
+
 	// goroutine to check for crashes and receive messages
 	go func(conn net.Conn) {
+		// the requests waiting for a response are released before the connection is reported as lost
+		lost := false
+		defer func() {
+			if lost {
+				closeChan <- struct{}{}
+			}
+		}()
+		defer close(done)
+
+		reader := netutils.NewMsgReader(conn, 0)
+
 		for {
 			select {
 			case <-stopCheckChan:
 				return
 			default:
-				resp, err := netutils.RecvMsg(conn)
-				if err != nil && err == io.EOF {
-					closeChan <- struct{}{}
+				heartbeatMutex.Lock()
+				conn.SetReadDeadline(time.Now().Add(heartbeatTimeout))
+				heartbeatMutex.Unlock()
+
+				resp, err := reader.Recv()
+				if err != nil && errors.Is(err, net.ErrClosed) {
+					// the connection was closed by us
+					return
+				} else if err != nil && err == io.EOF {
+					// the server closed the connection, such as when the player is kicked
+					lost = true
+					return
+				} else if err != nil && errors.Is(err, os.ErrDeadlineExceeded) {
+					// the server did not answer the heartbeats, give up the connection
+					conn.Close()
+
+					// unblock the request waiting for a response, if any
+					select {
+					case recvChan <- netResponse{err: errors.New(i18n.T("connection.notResponding"))}:
+					default:
+					}
+
+					lost = true
 					return
 				} else {
 					recvChan <- netResponse{msg: resp, err: err}
@@ -69,19 +206,168 @@ func initConn() error {
 		}
 	}(conn)
 
+	go heartbeat(done)
+
+	return nil
+}
+
+// wait for the response to the request which was just sent, the error is net.ErrClosed if the connection can no longer
+// receive messages
+// netMutex must be held by the caller
+func recvResponse() netResponse {
+	select {
+	case resp := <-recvChan:
+		return resp
+	case <-connDone:
+		return netResponse{err: net.ErrClosed}
+	}
+}
+
+// send a heartbeat every heartbeatInterval until the connection is closed
+func heartbeat(done chan struct{}) {
+	for {
+		rtt, err := requestPing()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+
+		heartbeatMutex.Lock()
+		if err == nil {
+			lastRTT = rtt
+			lastPong = time.Now()
+		}
+		interval := heartbeatInterval
+		heartbeatMutex.Unlock()
+
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// the response message is structured as follows:
+// pong [seconds between the heartbeats] [seconds after which the server closes a silent connection]
+//
+// e.g. "pong 5 20"
+func requestPing() (time.Duration, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	start := time.Now()
+
+	err := netutils.SendMsg(conn, "ping")
+	if err != nil {
+		return 0, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return 0, resp.err
+	}
+
+	rtt := time.Since(start)
+
+	fields := strings.Fields(resp.msg)
+	if len(fields) != 3 || fields[0] != "pong" {
+		return 0, errors.New(i18n.T("connection.badResponse", "ping"))
+	}
+
+	interval, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, err
+	}
+
+	timeout, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, err
+	}
+
+	heartbeatMutex.Lock()
+	heartbeatInterval = time.Duration(interval) * time.Second
+	heartbeatTimeout = time.Duration(timeout) * time.Second
+	heartbeatMutex.Unlock()
+
+	return rtt, nil
+}
+
+// describe the quality of the connection, based on the last heartbeats
+func connectionQuality() string {
+	heartbeatMutex.Lock()
+	defer heartbeatMutex.Unlock()
+
+	if lastPong.IsZero() {
+		return i18n.T("connection.connecting")
+	}
+
+	silence := time.Since(lastPong)
+	ms := lastRTT.Milliseconds()
+
+	switch {
+	case silence > heartbeatTimeout:
+		return i18n.T("connection.lost")
+	case silence > 2*heartbeatInterval:
+		return i18n.T("connection.unstable", int(silence.Seconds()))
+	case lastRTT < 150*time.Millisecond:
+		return i18n.T("connection.good", ms)
+	case lastRTT < 500*time.Millisecond:
+		return i18n.T("connection.fair", ms)
+	default:
+		return i18n.T("connection.poor", ms)
+	}
+}
+
+// send a request with the account credentials, used for both "login" and "register"
+func requestAccount(request string) error {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, request+" "+username+" "+password)
+	if err != nil {
+		return err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return resp.err
+	}
+
+	if resp.msg != "ok" {
+		return fmt.Errorf(resp.msg)
+	}
+
 	return nil
 }
 
-func requestJoin() error {
+func requestLogin() error {
+	return requestAccount("login")
+}
+
+func requestRegister() error {
+	return requestAccount("register")
+}
+
+// join a room, roomArg is a room id, an invite code, "new" or "new-private" and it can be empty to join the default room
+// secret is the password or the invite code of a private room, or the password of the new private room
+func requestJoin(roomArg, secret string) error {
 	netMutex.Lock()
 	defer netMutex.Unlock()
 
-	err := netutils.SendMsg(conn, "join "+username)
+	request := "join " + username
+	if roomArg != "" {
+		request += " " + roomArg
+	}
+	if secret != "" {
+		request += " " + secret
+	}
+
+	err := netutils.SendMsg(conn, request)
 	if err != nil {
 		return err
 	}
 
-	resp := <-recvChan
+	resp := recvResponse()
 	if resp.err != nil {
 		return resp.err
 	}
@@ -102,7 +388,7 @@ func requestPlayers() ([]string, error) {
 		return nil, err
 	}
 
-	playersCsv := <-recvChan
+	playersCsv := recvResponse()
 	if playersCsv.err != nil {
 		return nil, playersCsv.err
 	}
@@ -111,61 +397,165 @@ func requestPlayers() ([]string, error) {
 	return players, nil
 }
 
-func requestMaxPlayers() (uint, error) {
+// the response message is structured as follows:
+// [room id] [invite code, "-" if the room is public]\n
+// [minimum number of players] [maximum number of players]\n
+// [variant, either "classic" or "placement"]\n
+// [seconds a player has to place cards, 0 if there is no limit]\n
+// [y/n if the game is ranked]\n
+// [y/n if the game started]\n
+// [a line for each joined player, starting from the host, structured as "<name> <y/n if the player is ready>"]
+func requestRoom() (roomState, error) {
 	netMutex.Lock()
 	defer netMutex.Unlock()
 
-	err := netutils.SendMsg(conn, "get max-players")
+	err := netutils.SendMsg(conn, "get room")
 	if err != nil {
-		return 0, err
+		return roomState{}, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return roomState{}, resp.err
+	}
+
+	respLines := strings.Split(resp.msg, "\n")
+	if len(respLines) < 6 {
+		return roomState{}, errors.New(i18n.T("connection.badResponse", "get room"))
+	}
+
+	room := roomState{variant: respLines[2], ranked: respLines[4] == "y", started: respLines[5] == "y"}
+
+	id, code, _ := strings.Cut(respLines[0], " ")
+	room.id = id
+	if code != "-" {
+		room.inviteCode = code
 	}
 
-	maxPlayersStr := <-recvChan
-	if maxPlayersStr.err != nil {
-		return 0, maxPlayersStr.err
+	err = parseInts(respLines[1], &room.minPlayers, &room.maxPlayers)
+	if err != nil {
+		return roomState{}, err
 	}
 
-	maxPlayers, err := strconv.Atoi(maxPlayersStr.msg)
+	err = parseInts(respLines[3], &room.turnTime)
 	if err != nil {
-		return 0, err
+		return roomState{}, err
+	}
+
+	for _, line := range respLines[6:] {
+		name, ready, _ := strings.Cut(line, " ")
+		room.players = append(room.players, name)
+		room.ready = append(room.ready, ready == "y")
 	}
 
-	return uint(maxPlayers), nil
+	return room, nil
 }
 
-func requestCards() ([]cardutils.Card, error) {
+// the response message has a line for each public room, structured as follows:
+// [room id] [host name, "-" if the room is empty] [number of joined players] [maximum number of players] [y/n if the game started] [y/n if the game is ranked]
+func requestRooms() ([]roomInfo, error) {
 	netMutex.Lock()
 	defer netMutex.Unlock()
 
-	err := netutils.SendMsg(conn, "get cards")
+	err := netutils.SendMsg(conn, "get rooms")
 	if err != nil {
 		return nil, err
 	}
 
-	cardsStr := <-recvChan
-	if cardsStr.err != nil {
-		return nil, cardsStr.err
+	resp := recvResponse()
+	if resp.err != nil {
+		return nil, resp.err
 	}
 
-	cardsSp := strings.Split(cardsStr.msg, ",")
+	rooms := make([]roomInfo, 0)
+	if resp.msg == "" {
+		return rooms, nil
+	}
 
-	cards := make([]cardutils.Card, len(cardsSp))
+	for _, line := range strings.Split(resp.msg, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 6 {
+			return nil, errors.New(i18n.T("connection.badLine", line))
+		}
+
+		r := roomInfo{id: fields[0], started: fields[4] == "y", ranked: fields[5] == "y"}
+		if fields[1] != "-" {
+			r.host = fields[1]
+		}
 
-	for i := range cardsSp {
-		cards[i], err = cardutils.CardByName(cardsSp[i])
+		err = parseInts(fields[2]+" "+fields[3], &r.joined, &r.maxPlayers)
 		if err != nil {
 			return nil, err
 		}
+
+		rooms = append(rooms, r)
+	}
+
+	return rooms, nil
+}
+
+// change a setting of the room, only the host can do it
+func requestSetting(key, value string) error {
+	return requestSimple("set " + key + " " + value)
+}
+
+func requestReady(ready bool) error {
+	if ready {
+		return requestSimple("ready y")
+	}
+
+	return requestSimple("ready n")
+}
+
+// start the game, only the host can do it
+func requestStart() error {
+	return requestSimple("start")
+}
+
+func requestCards() (cardutils.Hand, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get cards")
+	if err != nil {
+		return cardutils.Hand{}, err
+	}
+
+	cardsStr := recvResponse()
+	if cardsStr.err != nil {
+		return cardutils.Hand{}, cardsStr.err
+	}
+
+	var cards cardutils.Hand
+
+	// the player has no cards left
+	if cardsStr.msg == "" {
+		return cards, nil
+	}
+
+	cardsSp := strings.Split(cardsStr.msg, ",")
+
+	for _, name := range cardsSp {
+		c, err := cardutils.CardByName(name)
+		if err != nil {
+			return cardutils.Hand{}, err
+		}
+
+		cards.Add(c)
 	}
 
 	return cards, nil
 }
 
 func StrToUpdate(response string) (update, error) {
-	respLines := strings.Split(response, " ")
+	respLines := strings.Split(response, "\n")
 
 	ud := update{}
 
+	if len(respLines) != 5 {
+		return update{}, errors.New(i18n.T("connection.badResponse", "get update"))
+	}
+
 	// first line
 	switch respLines[0] {
 	case "y":
@@ -177,11 +567,7 @@ func StrToUpdate(response string) (update, error) {
 	case "u":
 		ud.gameOver = false
 	default:
-		return update{}, fmt.Errorf("invalid line 0")
-	}
-
-	if ud.gameOver {
-		return ud, nil
+		return update{}, errors.New(i18n.T("connection.badLine", respLines[0]))
 	}
 
 	// second line
@@ -191,11 +577,14 @@ func StrToUpdate(response string) (update, error) {
 	case "n":
 		ud.playerTurn = false
 	default:
-		return update{}, fmt.Errorf("invalid line 1")
+		return update{}, errors.New(i18n.T("connection.badLine", respLines[1]))
 	}
 
 	// third line
 	cards := strings.Fields(respLines[2])
+	if len(cards) != 2 {
+		return update{}, errors.New(i18n.T("connection.badLine", respLines[2]))
+	}
 
 	cardsAmount, err := strconv.Atoi(cards[0])
 	if err != nil {
@@ -205,22 +594,40 @@ func StrToUpdate(response string) (update, error) {
 
 	cardRank, err := cardutils.RankByName(cards[1])
 	if err != nil {
-		return update{}, nil
+		return update{}, err
 	}
 	ud.cardRank = cardRank
 
+	// fourth line
+	placement, err := strconv.Atoi(respLines[3])
+	if err != nil {
+		return update{}, err
+	}
+	ud.placement = placement
+
+	// fifth line
+	shutdownIn, err := strconv.Atoi(respLines[4])
+	if err != nil {
+		return update{}, err
+	}
+	ud.shutdownIn = shutdownIn
+
 	return ud, nil
 }
 
 // the response message is structured as follows:
 // [y/n/u if the player won/lost or the game is not over yet]\n
 // [y/n if the current turn is the player's turn]\n
-// [cards which the last player said to have placed (<N> <card rank>, e.g. "3 seven")]
+// [cards which the last player said to have placed (<N> <card rank>, e.g. "3 seven")]\n
+// [placement of the player, 0 if the player did not finish yet]\n
+// [seconds before the server ends the game to shut down, -1 if it is not shutting down]
 //
 // e.g.
 // "u\n
 // n\n
-// 2 ace"
+// 2 ace\n
+// 0\n
+// -1"
 func requestUpdate() (update, error) {
 	netMutex.Lock()
 	defer netMutex.Unlock()
@@ -230,7 +637,7 @@ func requestUpdate() (update, error) {
 		return update{}, err
 	}
 
-	resp := <-recvChan
+	resp := recvResponse()
 	if resp.err != nil {
 		return update{}, resp.err
 	}
@@ -238,7 +645,121 @@ func requestUpdate() (update, error) {
 	return StrToUpdate(resp.msg)
 }
 
-func requestPlaceCards(cards []cardutils.Card) (bool, error) {
+// the response message has a line for each player who finished, from the first one, structured as follows:
+// [placement] [player name]
+//
+// once the game is over, all the players are included and players who did not finish may share the same placement
+func requestStandings() ([]standing, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get standings")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	standings := make([]standing, 0)
+	if resp.msg == "" {
+		return standings, nil
+	}
+
+	for _, line := range strings.Split(resp.msg, "\n") {
+		placementStr, name, _ := strings.Cut(line, " ")
+
+		placement, err := strconv.Atoi(placementStr)
+		if err != nil {
+			return nil, err
+		}
+
+		standings = append(standings, standing{placement: placement, name: name})
+	}
+
+	return standings, nil
+}
+
+// the response message is structured as follows:
+// [number of cards on the table]\n
+// [a "<name> <number of cards held>" pair for each joined player, separated by commas]\n
+// [a line for each play or doubt of the current game, from the oldest one]
+//
+// plays are structured as "p <name> <number of cards> <claimed rank>"
+// doubts are structured as "d <name> <right/wrong> <doubted name> <number of cards taken>"
+func requestTable() (tableState, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get table")
+	if err != nil {
+		return tableState{}, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return tableState{}, resp.err
+	}
+
+	lines := strings.Split(resp.msg, "\n")
+	if len(lines) < 2 {
+		return tableState{}, fmt.Errorf(resp.msg)
+	}
+
+	var table tableState
+
+	table.pile, err = strconv.Atoi(lines[0])
+	if err != nil {
+		return tableState{}, err
+	}
+
+	for _, pair := range strings.Split(lines[1], ",") {
+		name, heldStr, _ := strings.Cut(pair, " ")
+
+		held, err := strconv.Atoi(heldStr)
+		if err != nil {
+			return tableState{}, err
+		}
+
+		table.players = append(table.players, name)
+		table.held = append(table.held, held)
+	}
+
+	for _, line := range lines[2:] {
+		fields := strings.Fields(line)
+
+		var e tableEvent
+		switch {
+		case len(fields) == 4 && fields[0] == "p":
+			e.player = fields[1]
+			e.count, err = strconv.Atoi(fields[2])
+			if err == nil {
+				e.claim, err = cardutils.RankByName(fields[3])
+			}
+		case len(fields) == 5 && fields[0] == "d":
+			e.doubt = true
+			e.player = fields[1]
+			e.right = fields[2] == "right"
+			e.doubted = fields[3]
+			e.taken, err = strconv.Atoi(fields[4])
+		default:
+			err = errors.New(i18n.T("connection.badLine", line))
+		}
+
+		if err != nil {
+			return tableState{}, err
+		}
+
+		table.events = append(table.events, e)
+	}
+
+	return table, nil
+}
+
+// place cards claiming that they are of the rank claim, the error tells why the server refused them
+func requestPlaceCards(cards []cardutils.Card, claim cardutils.Rank) error {
 	netMutex.Lock()
 	defer netMutex.Unlock()
 
@@ -250,21 +771,21 @@ func requestPlaceCards(cards []cardutils.Card) (bool, error) {
 
 	cardsStr = strings.TrimRight(cardsStr, ",")
 
-	err := netutils.SendMsg(conn, "place "+cardsStr)
+	err := netutils.SendMsg(conn, "place "+cardsStr+" as "+cardutils.RankToString(claim))
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	resp := <-recvChan
+	resp := recvResponse()
 	if resp.err != nil {
-		return false, resp.err
+		return resp.err
 	}
 
 	if resp.msg != "ok" {
-		return false, nil
+		return fmt.Errorf(resp.msg)
 	}
 
-	return true, nil
+	return nil
 }
 
 // return nil if the doubt was correct (last player lied), otherwise return the array of cards currently in the table
@@ -280,31 +801,252 @@ func requestDubito() ([]cardutils.Card, error) {
 		return nil, err
 	}
 
-	resp := <-recvChan
+	resp := recvResponse()
 	if resp.err != nil {
 		return nil, resp.err
 	}
 
-	cards := make([]cardutils.Card, 0)
-	respLines := strings.Split(resp.msg, "\n")
+	return StrToDubito(resp.msg)
+}
+
+// parse the response to a dubito request, see requestDubito
+// any other response is an error sent by the server, such as when there is nothing to doubt
+func StrToDubito(response string) ([]cardutils.Card, error) {
+	respLines := strings.Split(response, "\n")
 
-	if respLines[0] == "right" {
+	if len(respLines) == 1 && respLines[0] == "right" {
 		return nil, nil
-	} else {
-		cardsStr := strings.Split(respLines[1], ",")
-		for _, c := range cardsStr {
-			c, err := cardutils.CardByName(c)
-			if err != nil {
-				return nil, err
-			}
+	} else if len(respLines) != 2 || respLines[0] != "wrong" {
+		return nil, errors.New(response)
+	}
 
-			cards = append(cards, c)
+	cards := make([]cardutils.Card, 0)
+	for _, c := range strings.Split(respLines[1], ",") {
+		c, err := cardutils.CardByName(c)
+		if err != nil {
+			return nil, err
 		}
+
+		cards = append(cards, c)
 	}
 
 	return cards, nil
 }
 
+// parse a line made of space-separated integers
+func parseInts(line string, ints ...*int) error {
+	fields := strings.Fields(line)
+	if len(fields) != len(ints) {
+		return errors.New(i18n.T("connection.badLine", line))
+	}
+
+	for i := range ints {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return err
+		}
+		*ints[i] = n
+	}
+
+	return nil
+}
+
+// the response message is structured as follows:
+// [player name]\n
+// [games played] [games won]\n
+// [bluffs attempted] [bluffs caught]\n
+// [doubts called] [doubts right]\n
+// [average number of cards held]
+func requestStats(name string) (playerStats, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get stats "+name)
+	if err != nil {
+		return playerStats{}, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return playerStats{}, resp.err
+	}
+
+	respLines := strings.Split(resp.msg, "\n")
+	if len(respLines) != 5 {
+		return playerStats{}, fmt.Errorf(resp.msg)
+	}
+
+	s := playerStats{name: respLines[0]}
+
+	err = parseInts(respLines[1], &s.gamesPlayed, &s.gamesWon)
+	if err != nil {
+		return playerStats{}, err
+	}
+
+	err = parseInts(respLines[2], &s.bluffsAttempted, &s.bluffsCaught)
+	if err != nil {
+		return playerStats{}, err
+	}
+
+	err = parseInts(respLines[3], &s.doubtsCalled, &s.doubtsRight)
+	if err != nil {
+		return playerStats{}, err
+	}
+
+	s.avgCardsHeld, err = strconv.ParseFloat(respLines[4], 64)
+	if err != nil {
+		return playerStats{}, err
+	}
+
+	return s, nil
+}
+
+// the response message has a line for each player, from the best one, structured as follows:
+// [player name] [games played] [games won]
+func requestLeaderboard() ([]playerStats, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get leaderboard")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	entries := make([]playerStats, 0)
+	if resp.msg == "" {
+		return entries, nil
+	}
+
+	for _, line := range strings.Split(resp.msg, "\n") {
+		name, counts, _ := strings.Cut(line, " ")
+
+		s := playerStats{name: name}
+		err = parseInts(counts, &s.gamesPlayed, &s.gamesWon)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, s)
+	}
+
+	return entries, nil
+}
+
+// return the ratings of the joined players, by name
+// the response message has a line for each player structured as follows:
+// [player name] [rating] [last rating change]
+//
+// both the rating and its change are "-" if the player has no rating
+func requestRatings() (map[string]rating, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get ratings")
+	if err != nil {
+		return nil, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	ratings := make(map[string]rating)
+	for _, line := range strings.Split(resp.msg, "\n") {
+		name, values, _ := strings.Cut(line, " ")
+
+		if values == "- -" {
+			ratings[name] = rating{rated: false}
+			continue
+		}
+
+		r := rating{rated: true}
+		err = parseInts(values, &r.rating, &r.change)
+		if err != nil {
+			return nil, err
+		}
+
+		ratings[name] = r
+	}
+
+	return ratings, nil
+}
+
+// send a request which only gets "ok" or an error message as response
+func requestSimple(request string) error {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, request)
+	if err != nil {
+		return err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return resp.err
+	}
+
+	if resp.msg != "ok" {
+		return fmt.Errorf(resp.msg)
+	}
+
+	return nil
+}
+
+func requestSendChat(text string) error {
+	return requestSimple("chat " + text)
+}
+
+// mute, unmute or kick a player, only the host can do it
+func requestModeration(action, name string) error {
+	return requestSimple(action + " " + name)
+}
+
+// return the chat messages with an id greater than or equal to since
+// the response message has a line for each message structured as follows:
+// [message id] [sender name] [text]
+func requestChat(since int) ([]chatMessage, error) {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	err := netutils.SendMsg(conn, "get chat "+strconv.Itoa(since))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := recvResponse()
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	messages := make([]chatMessage, 0)
+	if resp.msg == "" {
+		return messages, nil
+	}
+
+	for _, line := range strings.Split(resp.msg, "\n") {
+		lineSp := strings.SplitN(line, " ", 3)
+		if len(lineSp) != 3 {
+			return nil, fmt.Errorf(resp.msg)
+		}
+
+		id, err := strconv.Atoi(lineSp[0])
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, chatMessage{id: id, from: lineSp[1], text: lineSp[2]})
+	}
+
+	return messages, nil
+}
+
 func requestLeave() error {
 	netMutex.Lock()
 	defer netMutex.Unlock()
@@ -314,3 +1056,11 @@ func requestLeave() error {
 
 	return err
 }
+
+// close the connection without leaving, used when the player did not join
+func closeConn() error {
+	netMutex.Lock()
+	defer netMutex.Unlock()
+
+	return conn.Close()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+)
+
+func TestStrToDubito(t *testing.T) {
+	cards, err := StrToDubito("right")
+	if err != nil || cards != nil {
+		t.Errorf("right: got %v, %v", cards, err)
+	}
+
+	cards, err = StrToDubito("wrong\nace spades,two hearts")
+	want := []cardutils.Card{{Suit: cardutils.Spades, Rank: cardutils.Ace}, {Suit: cardutils.Hearts, Rank: cardutils.Two}}
+	if err != nil || len(cards) != len(want) || cards[0] != want[0] || cards[1] != want[1] {
+		t.Errorf("wrong: got %v, %v", cards, err)
+	}
+
+	// errors are sent on a single line
+	for _, resp := range []string{"there is nothing to doubt", "you cannot doubt yourself", "wrong", "right\nace spades"} {
+		if _, err := StrToDubito(resp); err == nil || err.Error() != resp {
+			t.Errorf("%q: got error %v", resp, err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/fileutils"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
+)
+
+// name of the settings file inside the config directory of the user, such as ~/.config/dubito on Linux
+const settingsFile = "client.json"
+
+// a saved server with the name used on it, the password is never saved
+type profile struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Address  string `json:"address"`
+	Port     uint16 `json:"port"`
+}
+
+// settings saved between launches
+type clientSettings struct {
+	Username      string    `json:"username"`
+	ServerAddress string    `json:"serverAddress"` // empty if the player never connected
+	ServerPort    uint16    `json:"serverPort"`
+	Profiles      []profile `json:"profiles"`
+	LastProfile   string    `json:"lastProfile"` // name of the last profile used, empty if none
+	DeckStyle     int       `json:"deckStyle"`
+	HandOrder     string    `json:"handOrder"` // "rank" or "suit"
+	Language      i18n.Lang `json:"language"`  // empty to use the language of the system
+}
+
+// saved server profiles, the current one is held by username, serverAddress and serverPort
+var profiles []profile
+
+// name of the last profile used
+var lastProfile string
+
+// language chosen by the player in the settings, empty to follow the language of the system
+var chosenLang i18n.Lang
+
+// return the path of the settings file
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "dubito", settingsFile), nil
+}
+
+// load the settings and apply them, the username and the server used last become the current ones
+// a missing settings file is not an error, the defaults are kept
+func loadSettings() error {
+	path, err := settingsPath()
+	if err != nil {
+		// there is no config directory, such as in the browser, so the settings are not saved
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var s clientSettings
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	profiles = s.Profiles
+	if s.DeckStyle >= 1 && s.DeckStyle <= 6 {
+		deckStyle = s.DeckStyle
+	}
+	if s.HandOrder == "suit" {
+		handOrder = cardutils.BySuit
+	}
+	if s.Language != "" {
+		if l, err := i18n.ParseLang(string(s.Language)); err == nil {
+			i18n.SetLang(l)
+			chosenLang = l
+		}
+	}
+
+	if p, ok := findProfile(s.LastProfile); ok {
+		useProfile(p)
+	}
+
+	// they may have been changed after choosing the profile
+	if s.ServerAddress != "" {
+		username = s.Username
+		serverAddress = s.ServerAddress
+		serverPort = s.ServerPort
+	}
+
+	return nil
+}
+
+// save the settings
+func saveSettings() error {
+	path, err := settingsPath()
+	if err != nil {
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	order := "rank"
+	if handOrder == cardutils.BySuit {
+		order = "suit"
+	}
+
+	s := clientSettings{
+		Username:      username,
+		ServerAddress: serverAddress,
+		ServerPort:    serverPort,
+		Profiles:      profiles,
+		LastProfile:   lastProfile,
+		DeckStyle:     deckStyle,
+		HandOrder:     order,
+		Language:      chosenLang,
+	}
+
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return fileutils.WriteAtomic(path, data, 0600)
+}
+
+// return the profile called name, ignoring the case
+func findProfile(name string) (profile, bool) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+
+	return profile{}, false
+}
+
+// make p the current profile
+func useProfile(p profile) {
+	username = p.Username
+	serverAddress = p.Address
+	serverPort = p.Port
+	lastProfile = p.Name
+}
+
+// save the current username, address and port as the profile called name, replacing the one with the same name
+func saveProfile(name string) {
+	p := profile{Name: name, Username: username, Address: serverAddress, Port: serverPort}
+	lastProfile = name
+
+	for i := range profiles {
+		if strings.EqualFold(profiles[i].Name, name) {
+			profiles[i] = p
+			return
+		}
+	}
+
+	profiles = append(profiles, p)
+}
+
+// delete the profile called name, if any
+func deleteProfile(name string) {
+	for i := range profiles {
+		if strings.EqualFold(profiles[i].Name, name) {
+			profiles = append(profiles[:i], profiles[i+1:]...)
+			break
+		}
+	}
+
+	if strings.EqualFold(lastProfile, name) {
+		lastProfile = ""
+	}
+}
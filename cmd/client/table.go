@@ -0,0 +1,173 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/EdoardoLaGreca/dubito/assets"
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
+)
+
+// size of a card in the pile
+const pileCardWidth, pileCardHeight = 50.0, 100.0
+
+// the pile shows at most this many cards, each one shifted by pileCardShift from the one below
+const pileMaxCards = 20
+const pileCardShift = 3.0
+
+// the table of the game: the players with the number of cards they hold, the face-down pile, the last claim and the history
+// of the plays and the doubts
+// it is only changed by the goroutine which follows the game
+type tableView struct {
+	w fyne.Window
+
+	players  []string
+	ratings  map[string]rating
+	cnvNames []*canvas.Text
+
+	pileCont   *fyne.Container // a face-down card for each card on the table, up to pileMaxCards
+	pileShown  int             // cards shown in pileCont
+	lblPile    *widget.Label
+	lblClaim   *widget.Label
+	lblHistory *widget.Label
+	scrHistory *container.Scroll
+}
+
+func newTableView(w fyne.Window, players []string, ratings map[string]rating) *tableView {
+	tv := &tableView{
+		w:          w,
+		players:    players,
+		ratings:    ratings,
+		cnvNames:   make([]*canvas.Text, len(players)),
+		pileCont:   container.NewWithoutLayout(),
+		lblPile:    widget.NewLabel(i18n.T("table.pile", 0)),
+		lblClaim:   widget.NewLabel(i18n.T("table.newPile")),
+		lblHistory: widget.NewLabel(""),
+	}
+
+	for i := range players {
+		tv.cnvNames[i] = canvas.NewText(fmtPlayerName(players[i], ratings), color.RGBA{R: 200, G: 200, B: 200, A: 255})
+	}
+
+	// Set first player
+	tv.cnvNames[0].Color = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+
+	tv.lblHistory.Wrapping = fyne.TextWrapWord
+	tv.scrHistory = container.NewVScroll(tv.lblHistory)
+	tv.scrHistory.SetMinSize(fyne.NewSize(250.0, 120.0))
+
+	return tv
+}
+
+// return the container with the players, the pile, the last claim and the history
+func (tv *tableView) container() *fyne.Container {
+	names := make([]fyne.CanvasObject, len(tv.cnvNames))
+	for i := range tv.cnvNames {
+		names[i] = tv.cnvNames[i]
+	}
+
+	playersCont := container.New(layout.NewHBoxLayout(), names...)
+
+	// the cards of the pile are placed by hand, the rectangle gives the room for the tallest pile
+	pileSpace := canvas.NewRectangle(color.Transparent)
+	pileSpace.SetMinSize(fyne.NewSize(pileCardWidth+pileMaxCards*pileCardShift, pileCardHeight+pileMaxCards*pileCardShift))
+	pileCont := container.NewMax(pileSpace, tv.pileCont)
+
+	lblHistoryTitle := widget.NewLabel(i18n.T("table.history"))
+	historyCont := container.NewBorder(lblHistoryTitle, nil, nil, nil, tv.scrHistory)
+
+	tableCont := container.NewHBox(pileCont, container.NewVBox(tv.lblPile, tv.lblClaim), historyCont)
+
+	return container.New(layout.NewVBoxLayout(), playersCont, tableCont)
+}
+
+// show the state of the table
+func (tv *tableView) update(table tableState) {
+	tv.updatePlayers(table)
+	tv.updatePile(table.pile)
+
+	// the last claim is the last play, unless a doubt emptied the table after it
+	tv.lblClaim.SetText(i18n.T("table.newPile"))
+	if n := len(table.events); table.pile > 0 && n > 0 && !table.events[n-1].doubt {
+		e := table.events[n-1]
+		tv.lblClaim.SetText(i18n.T("table.lastClaim", e.player, e.count, cardutils.LocalRankName(e.claim, i18n.CurrentLang())))
+	}
+
+	lines := make([]string, len(table.events))
+	for i, e := range table.events {
+		lines[i] = eventText(e)
+	}
+
+	text := strings.Join(lines, "\n")
+	if text != tv.lblHistory.Text {
+		tv.lblHistory.SetText(text)
+		tv.scrHistory.ScrollToBottom()
+	}
+}
+
+// show the number of cards held next to the name of each player
+func (tv *tableView) updatePlayers(table tableState) {
+	for i, name := range tv.players {
+		text := fmtPlayerName(name, tv.ratings)
+
+		// players who left are not in the table anymore
+		for j := range table.players {
+			if table.players[j] == name {
+				text = i18n.T("table.held", text, table.held[j])
+				break
+			}
+		}
+
+		if tv.cnvNames[i].Text != text {
+			tv.cnvNames[i].Text = text
+			tv.cnvNames[i].Refresh()
+		}
+	}
+}
+
+// show a face-down card for each card on the table, each one shifted up and to the right of the one below
+func (tv *tableView) updatePile(pile int) {
+	tv.lblPile.SetText(i18n.T("table.pile", pile))
+
+	shown := min(pile, pileMaxCards)
+	if shown == tv.pileShown {
+		return
+	}
+
+	img, err := assets.GetDeckAsset(deckStyle)
+	if err != nil {
+		dialog.ShowError(err, tv.w)
+		return
+	}
+
+	objects := make([]fyne.CanvasObject, shown)
+	for i := range objects {
+		cnvCard := canvas.NewImageFromImage(img)
+		cnvCard.FillMode = canvas.ImageFillContain
+		cnvCard.Resize(fyne.NewSize(pileCardWidth, pileCardHeight))
+		cnvCard.Move(fyne.NewPos(float32(i)*pileCardShift, float32(pileMaxCards-i)*pileCardShift))
+		objects[i] = cnvCard
+	}
+
+	tv.pileShown = shown
+	tv.pileCont.Objects = objects
+	tv.pileCont.Refresh()
+}
+
+// return the line of the history which tells about e
+func eventText(e tableEvent) string {
+	if !e.doubt {
+		return i18n.T("table.play", e.player, e.count, cardutils.LocalRankName(e.claim, i18n.CurrentLang()))
+	} else if e.right {
+		return i18n.T("table.doubtRight", e.player, e.doubted, e.taken)
+	}
+
+	return i18n.T("table.doubtWrong", e.player, e.doubted, e.taken)
+}
@@ -1,190 +1,536 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"image/color"
+	"net"
 	"strconv"
 	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
-	"github.com/EdoardoLaGreca/dubito/assets"
 	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
+	"github.com/EdoardoLaGreca/dubito/internal/netutils"
 )
 
 var deckStyle int = 1
-var selectedCards []cardutils.Card = make([]cardutils.Card, 0)
 
-// true if selectedCards contains card
-func selectedCardsContains(card cardutils.Card) bool {
-	for _, c := range selectedCards {
-		if c == card {
-			return true
-		}
-	}
+// how long to wait for servers on the local network to answer
+const discoveryTimeout = 2 * time.Second
 
-	return false
-}
+// how often the waiting room and the game ask the server for changes
+const pollInterval = 200 * time.Millisecond
 
-// remove card from selectedCards if it exists, otherwise do nothing
-func removeSelectedCard(card cardutils.Card) {
-	for i, c := range selectedCards {
-		if c == card {
-			selectedCards = append(selectedCards[:i], selectedCards[i+1:]...)
-			break
+func getSettingsContainer(w fyne.Window) *fyne.Container {
+	lblProfile := widget.NewLabel(i18n.T("settings.profile"))
+	profileNames := make([]string, len(profiles))
+	for i, p := range profiles {
+		profileNames[i] = p.Name
+	}
+	selProfile := widget.NewSelect(profileNames, nil)
+	selProfile.PlaceHolder = i18n.T("settings.chooseProfile")
+	if p, ok := findProfile(lastProfile); ok {
+		selProfile.SetSelected(p.Name)
+	}
+	selProfile.OnChanged = func(name string) {
+		if p, ok := findProfile(name); ok {
+			useProfile(p)
+
+			// show the username, address and port of the profile
+			w.SetContent(getSettingsContainer(w))
 		}
 	}
-}
 
-func getSettingsContainer(w fyne.Window) *fyne.Container {
-	lblUsername := widget.NewLabel("Username")
+	lblUsername := widget.NewLabel(i18n.T("settings.username"))
 	entUsername := widget.NewEntry()
 	entUsername.OnChanged = func(value string) {
 		username = value
 	}
 	entUsername.Text = username
 
-	lblAddress := widget.NewLabel("Server address")
+	lblPassword := widget.NewLabel(i18n.T("settings.password"))
+	entPassword := widget.NewPasswordEntry()
+	entPassword.OnChanged = func(value string) {
+		password = value
+	}
+	entPassword.Text = password
+
+	lblAddress := widget.NewLabel(i18n.T("settings.address"))
 	entAddress := widget.NewEntry()
 	entAddress.OnChanged = func(value string) {
 		serverAddress = value
 	}
 	entAddress.Text = serverAddress
 
-	lblPort := widget.NewLabel("Server port")
+	lblPort := widget.NewLabel(i18n.T("settings.port"))
 	entPort := widget.NewEntry()
 	entPort.OnChanged = func(value string) {
 		port, err := strconv.Atoi(value)
 		if err != nil || port >= 1<<16 {
-			dialog.ShowError(fmt.Errorf("invalid port number"), w)
+			dialog.ShowError(errors.New(i18n.T("settings.invalidPort")), w)
 		} else {
 			serverPort = uint16(port)
 		}
 	}
 	entPort.Text = strconv.Itoa(int(serverPort))
 
-	lblDeckStyle := widget.NewLabel("Deck style")
+	lblDeckStyle := widget.NewLabel(i18n.T("settings.deckStyle"))
 	cmbDeckStyle := widget.NewSelect(make([]string, 0), func(value string) {
 		styleNumber := strings.Fields(value)[1]
 		deckStyle, _ = strconv.Atoi(styleNumber)
 	})
 
 	for i := 1; i <= 6; i++ {
-		cmbDeckStyle.Options = append(cmbDeckStyle.Options, "Style "+strconv.Itoa(i))
+		cmbDeckStyle.Options = append(cmbDeckStyle.Options, i18n.T("settings.style", i))
+	}
+
+	cmbDeckStyle.SetSelected(i18n.T("settings.style", deckStyle))
+
+	lblLanguage := widget.NewLabel(i18n.T("settings.language"))
+	langNames := make([]string, len(i18n.Langs))
+	for i, l := range i18n.Langs {
+		langNames[i] = l.Name()
+	}
+	selLanguage := widget.NewSelect(langNames, nil)
+	selLanguage.SetSelected(i18n.CurrentLang().Name())
+	selLanguage.OnChanged = func(string) {
+		chosenLang = i18n.Langs[selLanguage.SelectedIndex()]
+		i18n.SetLang(chosenLang)
+
+		// show the settings in the new language
+		w.SetContent(getSettingsContainer(w))
 	}
 
-	cmbDeckStyle.SetSelected("Style " + strconv.Itoa(int(deckStyle)))
+	btnSaveProfile := widget.NewButton(i18n.T("settings.saveProfile"), func() {
+		showSaveProfile(w)
+	})
 
-	btnBack := widget.NewButton("Back", func() {
+	btnDeleteProfile := widget.NewButton(i18n.T("settings.deleteProfile"), func() {
+		if lastProfile == "" {
+			return
+		}
+
+		deleteProfile(lastProfile)
+		w.SetContent(getSettingsContainer(w))
+	})
+
+	btnBack := widget.NewButton(i18n.T("common.back"), func() {
+		err := saveSettings()
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
 		w.SetContent(getMenuContainer(w))
 	})
 
-	return container.New(layout.NewGridLayout(2), lblUsername, entUsername, lblAddress, entAddress, lblPort, entPort, lblDeckStyle, cmbDeckStyle, btnBack)
+	btnRegister := widget.NewButton(i18n.T("settings.createAccount"), func() {
+		createAccount(w)
+	})
+
+	btnDiscover := widget.NewButton(i18n.T("settings.discover"), func() {
+		showLocalServers(w)
+	})
+
+	return container.New(layout.NewGridLayout(2), lblProfile, selProfile, lblUsername, entUsername, lblPassword, entPassword, lblAddress, entAddress, lblPort, entPort, lblDeckStyle, cmbDeckStyle, lblLanguage, selLanguage, btnSaveProfile, btnDeleteProfile, btnDiscover, widget.NewLabel(""), btnBack, btnRegister)
 }
 
-func getWaitingRoomContainer(w fyne.Window, maxPlayers uint) *fyne.Container {
-	lblJoined := widget.NewLabel("0/" + strconv.Itoa(int(maxPlayers)) + " joined")
+// ask for a name and save the current username, address and port as a profile
+func showSaveProfile(w fyne.Window) {
+	entName := widget.NewEntry()
+	entName.SetText(lastProfile)
+	if lastProfile == "" {
+		entName.SetText(serverAddress)
+	}
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("settings.profileName"), entName),
+	}
+
+	dialog.ShowForm(i18n.T("settings.saveProfile"), i18n.T("common.save"), i18n.T("common.cancel"), items, func(save bool) {
+		name := strings.TrimSpace(entName.Text)
+		if !save || name == "" {
+			return
+		}
 
-	return container.New(layout.NewCenterLayout(), lblJoined)
+		saveProfile(name)
+		err := saveSettings()
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+
+		w.SetContent(getSettingsContainer(w))
+	}, w)
+}
+
+// look for servers on the local network and let the player choose one, which fills in the address and port
+func showLocalServers(w fyne.Window) {
+	servers, err := netutils.Discover(discoveryTimeout)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	} else if len(servers) == 0 {
+		dialog.ShowInformation(i18n.T("settings.discover"), i18n.T("discovery.none"), w)
+		return
+	}
+
+	var dlgServers dialog.Dialog
+
+	lstServers := widget.NewList(
+		func() int {
+			return len(servers)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, obj fyne.CanvasObject) {
+			s := servers[i]
+			obj.(*widget.Label).SetText(i18n.T("discovery.server", s.Name, s.Address, s.Port, s.Rooms, s.FreeSeats))
+		},
+	)
+	lstServers.OnSelected = func(i widget.ListItemID) {
+		serverAddress = servers[i].Address
+		serverPort = servers[i].Port
+		dlgServers.Hide()
+
+		// show the new address and port
+		w.SetContent(getSettingsContainer(w))
+	}
+
+	dlgServers = dialog.NewCustom(i18n.T("settings.discover"), i18n.T("common.cancel"), lstServers, w)
+	dlgServers.Resize(fyne.NewSize(400, 300))
+	dlgServers.Show()
 }
 
-func updateJoinedCount(label *widget.Label, joinedPlayers, maxPlayers uint) {
-	label.SetText(strconv.Itoa(int(joinedPlayers)) + "/" + strconv.Itoa(int(maxPlayers)) + " joined")
+// register the username and password on the server
+func createAccount(w fyne.Window) {
+	if password == "" {
+		dialog.ShowError(errors.New(i18n.T("account.choosePassword")), w)
+		return
+	}
+
+	err := initConn()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+	defer closeConn()
+
+	err = requestRegister()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	dialog.ShowInformation(i18n.T("account.created"), i18n.T("account.createdText", username), w)
 }
 
-// create a cards container
-func newCardsCont(w fyne.Window, lblSelectedCards *widget.Label, cards []cardutils.Card) *fyne.Container {
-	// use a grid layout of 1 row to divide the horizontal space in equal parts
-	cardsCont := container.New(layout.NewGridWrapLayout(fyne.NewSize(40.0, 80.0)))
+func getWaitingRoomContainer(w fyne.Window) *fyne.Container {
+	lblSettings := widget.NewLabel("")
+	lblPlayers := widget.NewLabel("")
 
-	for _, c := range cards {
-		img, err := assets.GetCardAsset(c)
+	chkReady := widget.NewCheck(i18n.T("waiting.ready"), func(ready bool) {
+		err := requestReady(ready)
 		if err != nil {
 			dialog.ShowError(err, w)
-			break
 		}
+	})
 
-		canvasImage := canvas.NewImageFromImage(img)
-		canvasImage.FillMode = canvas.ImageFillContain
+	btnSettings := widget.NewButton(i18n.T("waiting.changeSettings"), func() {
+		showRoomSettings(w)
+	})
 
-		rectSelected := canvas.NewRectangle(color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	btnKick := widget.NewButton(i18n.T("waiting.kick"), func() {
+		showKickDialog(w)
+	})
 
-		// save the card in a new variable so that the function literal doesn't reference the variable updated by the loop. otherwise it would
-		// cause function literals to all have the same value, which would always be the last card of the loop
-		currentCard := c
+	btnStart := widget.NewButton(i18n.T("waiting.start"), func() {
+		err := requestStart()
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	})
 
-		imgButton := widget.NewButton("", func() {
-			if selectedCardsContains(currentCard) {
-				removeSelectedCard(currentCard)
-				rectSelected.FillColor = color.RGBA{R: 0, G: 0, B: 0, A: 255}
-				rectSelected.Refresh()
-			} else {
-				if len(selectedCards) < 4 {
-					selectedCards = append(selectedCards, currentCard)
-					rectSelected.FillColor = color.RGBA{R: 0, G: 255, B: 0, A: 255}
-					rectSelected.Refresh()
-				} else {
-					dialog.ShowError(fmt.Errorf("you selected 4 cards already"), w)
-				}
-			}
-			lblSelectedCards.Text = fmt.Sprintf("You selected %d cards: %s", len(selectedCards), strings.Join(cardutils.CardsToString(selectedCards), ", "))
-			lblSelectedCards.Refresh()
-		})
+	btnLeave := widget.NewButton(i18n.T("common.leave"), func() {
+		requestLeave()
+		w.SetContent(getMenuContainer(w))
+	})
+
+	// only the host can see these
+	btnSettings.Hide()
+	btnKick.Hide()
+	btnStart.Hide()
+
+	lblConnection := widget.NewLabel("")
 
-		// button with a rectangle and an image on top of it
-		clickableImg := container.NewMax(imgButton, rectSelected, canvasImage)
+	return container.New(layout.NewVBoxLayout(), lblSettings, lblPlayers, chkReady, btnSettings, btnKick, btnStart, btnLeave, lblConnection)
+}
+
+// show the quality of the connection in lblConnection
+func updateConnectionLabel(lblConnection *widget.Label) {
+	lblConnection.SetText(i18n.T("connection.label", connectionQuality()))
+}
 
-		cardsCont.Add(clickableImg)
+// return the translated name of a variant, or the name used by the server if it is unknown
+func variantName(variant string) string {
+	if name, ok := i18n.Lookup("variant." + variant); ok {
+		return name
 	}
 
-	return cardsCont
+	return variant
 }
 
-func getGameContainer(w fyne.Window, players []string, cards []cardutils.Card) *fyne.Container {
-	cnvPlayers := make([]fyne.CanvasObject, len(players))
-	for i := range players {
-		cnvPlayers[i] = canvas.NewText(players[i], color.RGBA{R: 200, G: 200, B: 200, A: 255})
+// return true if the player is the host of the room
+func isHost(room roomState) bool {
+	return len(room.players) > 0 && strings.EqualFold(room.players[0], username)
+}
+
+// show the state of the room in the waiting room container
+func updateWaitingRoom(wrCont *fyne.Container, room roomState) {
+	lblSettings := wrCont.Objects[0].(*widget.Label)
+	lblPlayers := wrCont.Objects[1].(*widget.Label)
+	chkReady := wrCont.Objects[2].(*widget.Check)
+	lblConnection := wrCont.Objects[7].(*widget.Label)
+
+	updateConnectionLabel(lblConnection)
+
+	settingsText := i18n.T("waiting.room", room.id)
+	if room.inviteCode != "" {
+		settingsText += i18n.T("waiting.private", room.inviteCode)
+	}
+	settingsText += "\n"
+	settingsText += i18n.T("waiting.players", len(room.players), room.minPlayers, room.maxPlayers) + "\n"
+	settingsText += i18n.T("waiting.variant", variantName(room.variant)) + "\n"
+	if room.turnTime > 0 {
+		settingsText += i18n.T("waiting.turnTime", room.turnTime) + "\n"
+	} else {
+		settingsText += i18n.T("waiting.noTurnLimit") + "\n"
 	}
+	if room.ranked {
+		settingsText += i18n.T("waiting.ranked")
+	} else {
+		settingsText += i18n.T("waiting.unranked")
+	}
+	lblSettings.SetText(settingsText)
+
+	playersLines := make([]string, len(room.players))
+	for i, p := range room.players {
+		if i == 0 {
+			playersLines[i] = i18n.T("waiting.host", p)
+		} else if room.ready[i] {
+			playersLines[i] = i18n.T("waiting.isReady", p)
+		} else {
+			playersLines[i] = i18n.T("waiting.notReady", p)
+		}
+	}
+	lblPlayers.SetText(strings.Join(playersLines, "\n"))
 
-	// Set first player
-	cnvPlayers[0].(*canvas.Text).Color = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	// the host does not need to be ready, they start the game
+	if isHost(room) {
+		chkReady.Hide()
+		for _, obj := range wrCont.Objects[3:6] {
+			obj.Show()
+		}
+	} else {
+		chkReady.Show()
+		for _, obj := range wrCont.Objects[3:6] {
+			obj.Hide()
+		}
+	}
+}
 
-	playersCont := container.New(layout.NewHBoxLayout(), cnvPlayers...)
+// show a form to change the settings of the room
+func showRoomSettings(w fyne.Window) {
+	room, err := requestRoom()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	entMinPlayers := widget.NewEntry()
+	entMinPlayers.SetText(strconv.Itoa(room.minPlayers))
+
+	entMaxPlayers := widget.NewEntry()
+	entMaxPlayers.SetText(strconv.Itoa(room.maxPlayers))
+
+	// the variants are shown translated and sent with the name used by the server
+	variants := []string{"classic", "placement"}
+	variantNames := make([]string, len(variants))
+	for i, v := range variants {
+		variantNames[i] = variantName(v)
+	}
+	selVariant := widget.NewSelect(variantNames, nil)
+	selVariant.SetSelected(variantName(room.variant))
+
+	entTurnTime := widget.NewEntry()
+	entTurnTime.SetText(strconv.Itoa(room.turnTime))
+
+	chkRanked := widget.NewCheck("", nil)
+	chkRanked.SetChecked(room.ranked)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("roomSettings.minPlayers"), entMinPlayers),
+		widget.NewFormItem(i18n.T("roomSettings.maxPlayers"), entMaxPlayers),
+		widget.NewFormItem(i18n.T("roomSettings.variant"), selVariant),
+		widget.NewFormItem(i18n.T("roomSettings.turnTime"), entTurnTime),
+		widget.NewFormItem(i18n.T("roomSettings.ranked"), chkRanked),
+	}
+
+	dialog.ShowForm(i18n.T("roomSettings.title"), i18n.T("common.save"), i18n.T("common.cancel"), items, func(save bool) {
+		if !save {
+			return
+		}
+
+		variant := ""
+		if i := selVariant.SelectedIndex(); i >= 0 {
+			variant = variants[i]
+		}
+
+		rankedGame := "n"
+		if chkRanked.Checked {
+			rankedGame = "y"
+		}
+
+		// each setting is checked against the current value of the others, so the maximum goes first when raising both
+		// the minimum and the maximum, and the minimum goes first when lowering them
+		settings := [][2]string{
+			{"max-players", entMaxPlayers.Text},
+			{"min-players", entMinPlayers.Text},
+			{"variant", variant},
+			{"turn-time", entTurnTime.Text},
+			{"ranked", rankedGame},
+		}
+		if maxPlayers, err := strconv.Atoi(entMaxPlayers.Text); err == nil && maxPlayers < room.minPlayers {
+			settings[0], settings[1] = settings[1], settings[0]
+		}
 
-	// initially, the last card is the deck style
-	img, err := assets.GetDeckAsset(deckStyle)
+		for _, setting := range settings {
+			err := requestSetting(setting[0], setting[1])
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+		}
+	}, w)
+}
+
+// show a dialog to choose a player to kick from the room
+func showKickDialog(w fyne.Window) {
+	room, err := requestRoom()
 	if err != nil {
 		dialog.ShowError(err, w)
+		return
+	}
+
+	// the host cannot kick themselves
+	selPlayer := widget.NewSelect(room.players[1:], nil)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("kick.player"), selPlayer),
+	}
+
+	dialog.ShowForm(i18n.T("waiting.kick"), i18n.T("kick.confirm"), i18n.T("common.cancel"), items, func(kick bool) {
+		if !kick || selPlayer.Selected == "" {
+			return
+		}
+
+		err := requestModeration("kick", selPlayer.Selected)
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+}
+
+// ask which rank the selected cards are claimed to be, then place them
+func showPlaceDialog(w fyne.Window, hv *handView) {
+	cards := hv.selection()
+	if len(cards) == 0 {
+		return
+	}
+
+	lang := i18n.CurrentLang()
+	claim, free := hv.claim()
+
+	lblCards := widget.NewLabel(strings.Join(cardutils.LocalCardNames(cards, lang), ", "))
+
+	// warn the player if the cards do not match the claim, they may not want to bluff
+	lblWarning := widget.NewLabel("")
+	lblWarning.Wrapping = fyne.TextWrapWord
+
+	rankNames := make([]string, 0, cardutils.King)
+	for r := cardutils.Ace; r <= cardutils.King; r++ {
+		rankNames = append(rankNames, cardutils.LocalRankName(r, lang))
 	}
 
-	lastCardPlaced := canvas.NewImageFromImage(img)
-	lastCardPlaced.FillMode = canvas.ImageFillContain
-	lastCardPlaced.SetMinSize(fyne.NewSize(100.0, 200.0))
-	lastCardCont := container.New(layout.NewBorderLayout(nil, nil, nil, nil), lastCardPlaced)
+	selRank := widget.NewSelect(rankNames, nil)
+	selRank.SetSelectedIndex(int(claim) - 1)
+	selRank.OnChanged = func(string) {
+		claim = cardutils.Rank(selRank.SelectedIndex() + 1)
 
-	lblSelectedCards := widget.NewLabel("You selected 0 cards")
+		bluffed := 0
+		for _, c := range cards {
+			if c.Rank != claim {
+				bluffed++
+			}
+		}
+
+		if bluffed > 0 {
+			lblWarning.SetText(i18n.T("place.bluff", bluffed, len(cards), cardutils.LocalRankName(claim, lang)))
+		} else {
+			lblWarning.SetText("")
+		}
+	}
+	selRank.OnChanged(selRank.Selected)
+
+	itemRank := widget.NewFormItem(i18n.T("place.claim"), selRank)
+	if free {
+		itemRank.HintText = i18n.T("place.free")
+	} else {
+		// the server refuses any other rank
+		itemRank.HintText = i18n.T("place.required", cardutils.LocalRankName(claim, lang))
+		selRank.Disable()
+	}
 
-	cardsCont := newCardsCont(w, lblSelectedCards, cards)
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("place.cards"), lblCards),
+		itemRank,
+		widget.NewFormItem("", lblWarning),
+	}
 
-	btnPlace := widget.NewButton("Place cards", func() {
-		if len(selectedCards) == 0 {
+	dialog.ShowForm(i18n.T("game.place"), i18n.T("place.confirm"), i18n.T("common.cancel"), items, func(place bool) {
+		if !place {
 			return
 		}
 
-		ok, err := requestPlaceCards(selectedCards)
+		err := requestPlaceCards(cards, claim)
 		if err != nil {
 			dialog.ShowError(err, w)
-		} else if !ok {
-			dialog.ShowError(fmt.Errorf("unable to place cards"), w)
+			return
 		}
+
+		hv.removeSelected()
+	}, w)
+}
+
+// format the name of a player along with their rating, if any
+func fmtPlayerName(name string, ratings map[string]rating) string {
+	r, ok := ratings[name]
+	if !ok || !r.rated {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%d, %+d)", name, r.rating, r.change)
+}
+
+func getGameContainer(w fyne.Window, tv *tableView, hv *handView) *fyne.Container {
+	btnPlace := widget.NewButton(i18n.T("game.place"), func() {
+		showPlaceDialog(w, hv)
 	})
 
-	btnDubito := widget.NewButton("Dubito!", func() {
+	btnDubito := widget.NewButton(i18n.T("game.dubito"), func() {
 		tableCards, err := requestDubito()
 		if err != nil {
 			dialog.ShowError(err, w)
@@ -192,37 +538,201 @@ func getGameContainer(w fyne.Window, players []string, cards []cardutils.Card) *
 		}
 
 		if tableCards == nil {
-			dialog.ShowInformation("Correct!", "You doubted well! :)", w)
+			dialog.ShowInformation(i18n.T("game.right"), i18n.T("game.rightText"), w)
 		} else {
-			dialog.ShowInformation("Wrong!", "You doubted wrong... :(", w)
+			dialog.ShowInformation(i18n.T("game.wrong"), i18n.T("game.wrongText"), w)
 
-			// add cards
-			cardsCont = newCardsCont(w, lblSelectedCards, append(cards, tableCards...))
-			cardsCont.Refresh()
+			// the player takes the cards on the table
+			hv.addCards(tableCards...)
 		}
 
 	})
 
 	btnPlace.Hide()
 
-	btnLeave := widget.NewButton("Leave", func() {
+	btnLeave := widget.NewButton(i18n.T("common.leave"), func() {
 		requestLeave()
 		w.SetContent(getMenuContainer(w))
 	})
 
-	return container.New(layout.NewVBoxLayout(), playersCont, lastCardCont, cardsCont, lblSelectedCards, btnPlace, btnDubito, btnLeave)
+	lblConnection := widget.NewLabel("")
+
+	gameCont := container.New(layout.NewVBoxLayout(), tv.container(), hv.container(), hv.lblSelected, btnPlace, btnDubito, btnLeave, lblConnection)
+	chatCont := getChatContainer(w)
+
+	return container.New(layout.NewBorderLayout(nil, nil, nil, chatCont), chatCont, gameCont)
+}
+
+// send a chat message, the host can also moderate the chat with "/mute <name>", "/unmute <name>" and "/kick <name>"
+func sendChat(w fyne.Window, text string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return
+	}
+
+	var err error
+
+	switch fields[0] {
+	case "/mute", "/unmute", "/kick":
+		if len(fields) != 2 {
+			err = errors.New(i18n.T("chat.usage", fields[0]))
+		} else {
+			err = requestModeration(strings.TrimPrefix(fields[0], "/"), fields[1])
+		}
+	default:
+		err = requestSendChat(text)
+	}
+
+	if err != nil {
+		dialog.ShowError(err, w)
+	}
+}
+
+func getChatContainer(w fyne.Window) *fyne.Container {
+	lblChat := widget.NewLabel("")
+	lblChat.Wrapping = fyne.TextWrapWord
+
+	scrChat := container.NewVScroll(lblChat)
+	scrChat.SetMinSize(fyne.NewSize(250.0, 300.0))
+
+	entMessage := widget.NewEntry()
+	entMessage.SetPlaceHolder(i18n.T("chat.placeholder"))
+	entMessage.OnSubmitted = func(text string) {
+		sendChat(w, text)
+		entMessage.SetText("")
+	}
+
+	btnSend := widget.NewButton(i18n.T("chat.send"), func() {
+		entMessage.OnSubmitted(entMessage.Text)
+	})
+
+	msgCont := container.New(layout.NewBorderLayout(nil, nil, nil, btnSend), btnSend, entMessage)
+
+	return container.New(layout.NewBorderLayout(nil, msgCont, nil, nil), msgCont, scrChat)
+}
+
+// add the messages to the chat
+func updateChat(scrChat *container.Scroll, messages []chatMessage) {
+	lblChat := scrChat.Content.(*widget.Label)
+
+	for _, m := range messages {
+		if lblChat.Text != "" {
+			lblChat.Text += "\n"
+		}
+
+		if m.from == "*" {
+			lblChat.Text += m.text
+		} else {
+			lblChat.Text += m.from + ": " + m.text
+		}
+	}
+
+	lblChat.Refresh()
+	scrChat.ScrollToBottom()
+}
+
+// format the statistics of a player to be shown to the user
+func statsToText(s playerStats) string {
+	text := i18n.T("stats.won", s.gamesWon, s.gamesPlayed) + "\n"
+	text += i18n.T("stats.bluffs", s.bluffsCaught, s.bluffsAttempted) + "\n"
+
+	if s.doubtsCalled > 0 {
+		accuracy := 100 * float64(s.doubtsRight) / float64(s.doubtsCalled)
+		text += i18n.T("stats.doubtsAccuracy", s.doubtsRight, s.doubtsCalled, accuracy) + "\n"
+	} else {
+		text += i18n.T("stats.doubts", 0, 0) + "\n"
+	}
+
+	text += i18n.T("stats.avgCards", s.avgCardsHeld)
+
+	return text
+}
+
+func getLeaderboardContainer(w fyne.Window, entries []playerStats) *fyne.Container {
+	lblTitle := widget.NewLabel(i18n.T("leaderboard.title"))
+	if len(entries) == 0 {
+		lblTitle.Text = i18n.T("leaderboard.empty")
+	}
+
+	lstEntries := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			e := entries[id]
+			obj.(*widget.Label).SetText(i18n.T("leaderboard.entry", id+1, e.name, e.gamesWon, e.gamesPlayed))
+		},
+	)
+
+	lstEntries.OnSelected = func(id widget.ListItemID) {
+		lstEntries.Unselect(id)
+
+		s, err := requestStats(entries[id].name)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		dialog.ShowInformation(s.name, statsToText(s), w)
+	}
+
+	btnBack := widget.NewButton(i18n.T("common.back"), func() {
+		closeConn()
+		w.SetContent(getMenuContainer(w))
+	})
+
+	return container.New(layout.NewBorderLayout(lblTitle, btnBack, nil, nil), lblTitle, btnBack, lstEntries)
+}
+
+// connect to the server and show the leaderboard
+func showLeaderboard(w fyne.Window) {
+	err := initConn()
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	entries, err := requestLeaderboard()
+	if err != nil {
+		closeConn()
+		dialog.ShowError(err, w)
+		return
+	}
+
+	w.SetContent(getLeaderboardContainer(w, entries))
 }
 
 func getMenuContainer(w fyne.Window) *fyne.Container {
-	btnNewGame := widget.NewButton("New game", func() {
+	btnNewGame := widget.NewButton(i18n.T("menu.newGame"), func() {
 		newGame(w)
 	})
 
-	btnSettings := widget.NewButton("Settings", func() {
+	btnLeaderboard := widget.NewButton(i18n.T("menu.leaderboard"), func() {
+		showLeaderboard(w)
+	})
+
+	btnSettings := widget.NewButton(i18n.T("menu.settings"), func() {
 		w.SetContent(getSettingsContainer(w))
 	})
 
-	return container.New(layout.NewGridLayoutWithColumns(1), btnNewGame, btnSettings)
+	objects := []fyne.CanvasObject{btnNewGame, btnLeaderboard, btnSettings}
+
+	// the saved profiles connect with a click
+	if len(profiles) > 0 {
+		objects = append(objects, widget.NewLabel(i18n.T("menu.quickConnect")))
+	}
+	for _, p := range profiles {
+		p := p
+		objects = append(objects, widget.NewButton(i18n.T("menu.profile", p.Name, p.Username, p.Address, p.Port), func() {
+			useProfile(p)
+			newGame(w)
+		}))
+	}
+
+	return container.New(layout.NewGridLayoutWithColumns(1), objects...)
 }
 
 // show a dialog containing the error (if not nil) and load the main menu container
@@ -238,10 +748,35 @@ func connClosingHandler(w fyne.Window) {
 	// the connection is lost
 	<-closeChan
 
-	dialog.ShowError(fmt.Errorf("connection lost"), w)
+	dialog.ShowError(errors.New(i18n.T("connection.closed")), w)
 	w.SetContent(getMenuContainer(w))
 }
 
+// show the final standings
+func showGameOver(w fyne.Window, playerWon bool) {
+	text := i18n.T("game.lostText")
+	if playerWon {
+		text = i18n.T("game.wonText")
+	}
+
+	standings, err := requestStandings()
+	if err != nil {
+		dialog.ShowError(err, w)
+	} else {
+		text += "\n"
+		for _, s := range standings {
+			text += fmt.Sprintf("\n%d. %s", s.placement, s.name)
+		}
+	}
+
+	if playerWon {
+		dialog.ShowInformation(i18n.T("game.won"), text, w)
+	} else {
+		dialog.ShowInformation(i18n.T("game.lost"), text, w)
+	}
+}
+
+// connect to the server and show the public rooms
 func newGame(w fyne.Window) {
 	err := initConn()
 	if err != nil {
@@ -251,85 +786,261 @@ func newGame(w fyne.Window) {
 
 	go connClosingHandler(w)
 
-	err = requestJoin()
+	// the username and the server are restored on the next launch
+	err = saveSettings()
 	if err != nil {
 		dialog.ShowError(err, w)
+	}
+
+	if password != "" {
+		err = requestLogin()
+		if err != nil {
+			closeConn()
+			dialog.ShowError(err, w)
+			return
+		}
+	}
+
+	showRooms(w)
+}
+
+// show the list of public rooms, which can be joined by clicking them
+func showRooms(w fyne.Window) {
+	rooms, err := requestRooms()
+	if err != nil {
+		closeConn()
+		backToMainMenu(w, err)
 		return
 	}
 
-	w.SetTitle("Dubito | in game as " + username)
+	w.SetContent(getRoomsContainer(w, rooms))
+}
+
+func getRoomsContainer(w fyne.Window, rooms []roomInfo) *fyne.Container {
+	lblTitle := widget.NewLabel(i18n.T("rooms.title"))
+
+	lstRooms := widget.NewList(
+		func() int {
+			return len(rooms)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(i widget.ListItemID, obj fyne.CanvasObject) {
+			r := rooms[i]
+
+			text := i18n.T("rooms.entry", r.id, r.joined, r.maxPlayers)
+			if r.host != "" {
+				text += i18n.T("rooms.host", r.host)
+			}
+			if r.ranked {
+				text += i18n.T("rooms.ranked")
+			}
+			if r.started {
+				text += i18n.T("rooms.playing")
+			}
+
+			obj.(*widget.Label).SetText(text)
+		},
+	)
+	lstRooms.OnSelected = func(i widget.ListItemID) {
+		lstRooms.Unselect(i)
+		joinRoom(w, rooms[i].id, "")
+	}
+
+	btnRefresh := widget.NewButton(i18n.T("rooms.refresh"), func() {
+		showRooms(w)
+	})
+
+	btnCreate := widget.NewButton(i18n.T("rooms.create"), func() {
+		joinRoom(w, "new", "")
+	})
+
+	btnCreatePrivate := widget.NewButton(i18n.T("rooms.createPrivate"), func() {
+		entPassword := widget.NewPasswordEntry()
+
+		items := []*widget.FormItem{
+			widget.NewFormItem(i18n.T("rooms.passwordOptional"), entPassword),
+		}
+
+		dialog.ShowForm(i18n.T("rooms.createPrivate"), i18n.T("rooms.createConfirm"), i18n.T("common.cancel"), items, func(create bool) {
+			if create {
+				joinRoom(w, "new-private", entPassword.Text)
+			}
+		}, w)
+	})
+
+	btnJoinPrivate := widget.NewButton(i18n.T("rooms.joinPrivate"), func() {
+		entRoom := widget.NewEntry()
+		entSecret := widget.NewPasswordEntry()
+
+		items := []*widget.FormItem{
+			widget.NewFormItem(i18n.T("rooms.inviteOrRoom"), entRoom),
+			widget.NewFormItem(i18n.T("rooms.password"), entSecret),
+		}
+
+		dialog.ShowForm(i18n.T("rooms.joinPrivate"), i18n.T("rooms.joinConfirm"), i18n.T("common.cancel"), items, func(join bool) {
+			if join && entRoom.Text != "" {
+				joinRoom(w, strings.TrimSpace(entRoom.Text), entSecret.Text)
+			}
+		}, w)
+	})
+
+	btnBack := widget.NewButton(i18n.T("common.back"), func() {
+		closeConn()
+		w.SetContent(getMenuContainer(w))
+	})
 
-	maxPlayers, err := requestMaxPlayers()
+	buttons := container.New(layout.NewGridLayoutWithColumns(1), btnRefresh, btnCreate, btnCreatePrivate, btnJoinPrivate, btnBack)
+
+	return container.New(layout.NewBorderLayout(lblTitle, buttons, nil, nil), lblTitle, buttons, lstRooms)
+}
+
+// join a room and play, see requestJoin for roomArg and secret
+func joinRoom(w fyne.Window, roomArg, secret string) {
+	err := requestJoin(roomArg, secret)
 	if err != nil {
+		// the player can pick another room
 		dialog.ShowError(err, w)
 		return
 	}
 
-	wrCont := getWaitingRoomContainer(w, maxPlayers)
+	w.SetTitle(i18n.T("game.title", username))
+
+	wrCont := getWaitingRoomContainer(w)
 	w.SetContent(wrCont)
 
-	lblJoined := wrCont.Objects[0].(*widget.Label)
+	// the callback which called this function must return, or the buttons of the waiting room would not respond
+	go playInRoom(w, wrCont)
+}
 
-	var players []string
+// wait for the host to start the game and play it
+func playInRoom(w fyne.Window, wrCont *fyne.Container) {
+	var room roomState
+	var err error
 
-	// wait until all players joined
-	for len(players) < int(maxPlayers) {
-		var err error
-		players, err = requestPlayers()
-		if err != nil {
+	// wait until the host starts the game
+	for {
+		room, err = requestRoom()
+		if errors.Is(err, net.ErrClosed) {
+			// the player left
+			return
+		} else if err != nil {
 			backToMainMenu(w, err)
 			return
 		}
 
-		updateJoinedCount(lblJoined, uint(len(players)), maxPlayers)
-		time.Sleep(time.Duration(200 * time.Millisecond))
+		if room.started {
+			break
+		}
+
+		updateWaitingRoom(wrCont, room)
+		time.Sleep(pollInterval)
 	}
 
+	players := room.players
+
 	cards, err := requestCards()
 	if err != nil {
 		backToMainMenu(w, err)
 		return
 	}
 
-	gameCont := getGameContainer(w, players, cards)
+	ratings, err := requestRatings()
+	if err != nil {
+		backToMainMenu(w, err)
+		return
+	}
+
+	tv := newTableView(w, players, ratings)
+	hv := newHandView(w, cards)
+	gameCont := getGameContainer(w, tv, hv)
 	w.SetContent(gameCont)
 
-	cnvLastCard := gameCont.Objects[1].(*fyne.Container).Objects[0].(*canvas.Image)
-	btnPlace := gameCont.Objects[4].(*widget.Button)
+	chatCont := gameCont.Objects[0].(*fyne.Container)
+	scrChat := chatCont.Objects[1].(*container.Scroll)
+	btnPlace := gameCont.Objects[1].(*fyne.Container).Objects[3].(*widget.Button)
+	lblConnection := gameCont.Objects[1].(*fyne.Container).Objects[6].(*widget.Label)
 
 	// goroutine to perform actions based on turns
 	go func(w fyne.Window) {
+		// true once the player has been told that they finished
+		finishNotified := false
+
+		// true once the player has been told that the server is shutting down
+		shutdownNotified := false
+
+		// id of the next chat message to get
+		nextChatID := 0
+
+		// the cards of the player and the table are asked again when the game changes
+		var lastUpdate update
+
 		for {
+			messages, err := requestChat(nextChatID)
+			if err == nil && len(messages) > 0 {
+				updateChat(scrChat, messages)
+				nextChatID = messages[len(messages)-1].id + 1
+			}
+
+			updateConnectionLabel(lblConnection)
+
 			ud, err := requestUpdate()
-			if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				// the player left or the connection was lost
+				return
+			} else if err != nil {
+				// give the server time to recover instead of flooding it with requests
+				time.Sleep(pollInterval)
 				continue
 			}
 
 			if ud.gameOver {
-				if ud.playerWon {
-					dialog.ShowInformation("You won!", "Congrats, you won this game! :)", w)
-				} else {
-					dialog.ShowInformation("You lost...", "You lost this game. :(", w)
-				}
+				btnPlace.Hide()
+				showGameOver(w, ud.playerWon)
+				return
+			}
+
+			if ud.playerTurn {
+				btnPlace.Show()
+			} else {
+				btnPlace.Hide()
+			}
+
+			// the cards are claimed to be of the rank after the one of the last play, while the player who starts the pile
+			// can claim any rank, the current one is suggested
+			if ud.cardsAmount == 0 {
+				hv.setClaim(ud.cardRank, true)
 			} else {
-				if ud.playerTurn {
-					btnPlace.Show()
-				} else {
-					btnPlace.Hide()
+				hv.setClaim(cardutils.NextRank(ud.cardRank), false)
+			}
+
+			// the hand can change because of the others, such as when they doubt of a bluff of the player
+			if ud != lastUpdate {
+				lastUpdate = ud
+
+				cards, err := requestCards()
+				if err == nil {
+					hv.setCards(cards)
+				}
+
+				table, err := requestTable()
+				if err == nil {
+					tv.update(table)
 				}
 			}
 
-			// update last card
-			newLastCard := cardutils.Card{Suit: cardutils.Spades, Rank: ud.cardRank}
-			newLastCardAsset, err := assets.GetCardAsset(newLastCard)
-			if err != nil {
-				dialog.ShowError(err, w)
-				continue
+			if ud.shutdownIn >= 0 && !shutdownNotified {
+				shutdownNotified = true
+				dialog.ShowInformation(i18n.T("game.shutdown"), i18n.T("game.shutdownText", ud.shutdownIn), w)
+			}
+
+			if ud.placement > 0 && !finishNotified {
+				finishNotified = true
+				dialog.ShowInformation(i18n.T("game.finished"), i18n.T("game.finishedText", ud.placement), w)
 			}
-			cnvLastCard.Image = newLastCardAsset
-			cnvLastCard.Refresh()
 
-			time.Sleep(200 * time.Millisecond)
+			time.Sleep(pollInterval)
 		}
 	}(w)
 }
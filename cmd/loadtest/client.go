@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/netutils"
+)
+
+// longest time to wait for a response
+const requestTimeout = 10 * time.Second
+
+// time to wait before sending again a request refused by the rate limit of the server
+const rateLimitBackoff = 100 * time.Millisecond
+
+// a room shared by some simulated clients, the first one is the host
+type simRoom struct {
+	size int
+
+	// closed once the host created the room on the server
+	created chan struct{}
+	id      string
+
+	// closed when the host starts the next game, it is replaced by a new channel meanwhile
+	started      chan struct{}
+	startedMutex sync.Mutex
+}
+
+func newSimRoom(size int) *simRoom {
+	return &simRoom{size: size, created: make(chan struct{}), started: make(chan struct{})}
+}
+
+// return the channel closed when the next game starts, call it before getting ready
+func (sr *simRoom) nextGame() chan struct{} {
+	sr.startedMutex.Lock()
+	defer sr.startedMutex.Unlock()
+
+	return sr.started
+}
+
+// tell the other clients that a game started
+func (sr *simRoom) gameStarted() {
+	sr.startedMutex.Lock()
+	defer sr.startedMutex.Unlock()
+
+	close(sr.started)
+	sr.started = make(chan struct{})
+}
+
+// a simulated player, it only touches its own fields so that no locking is needed
+type simClient struct {
+	name  string
+	host  bool
+	room  *simRoom
+	rng   *rand.Rand
+	delay time.Duration
+
+	conn   net.Conn
+	reader *netutils.MsgReader
+
+	latencies map[string][]time.Duration // by request type, such as "place" or "get update"
+	errors    map[string]int             // by description
+	refused   int                        // requests refused by the rules of the game, such as a doubt called too late
+	games     int                        // games played until the end, only counted by the host
+}
+
+func newSimClient(id int, room *simRoom, host bool) *simClient {
+	return &simClient{
+		name:      "load" + strconv.Itoa(id),
+		host:      host,
+		room:      room,
+		rng:       rand.New(rand.NewSource(seed + int64(id))),
+		delay:     delay,
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+	}
+}
+
+func (c *simClient) addError(format string, a ...any) {
+	c.errors[fmt.Sprintf(format, a...)]++
+}
+
+// send a request and wait for its response, recording how long it took
+// requests refused by the rate limit are counted as errors and sent again
+func (c *simClient) request(req string) (string, error) {
+	kind, _, _ := strings.Cut(req, " ")
+	if kind == "get" {
+		kind = strings.Join(strings.Fields(req)[:2], " ")
+	}
+
+	for {
+		c.conn.SetDeadline(time.Now().Add(requestTimeout))
+		start := time.Now()
+
+		err := netutils.SendMsg(c.conn, req)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := c.reader.Recv()
+		if err != nil {
+			return "", err
+		}
+
+		c.latencies[kind] = append(c.latencies[kind], time.Since(start))
+
+		if resp != "too many requests, slow down" {
+			return resp, nil
+		}
+
+		c.addError("rate limited")
+		time.Sleep(rateLimitBackoff)
+	}
+}
+
+// wait for the delay between two actions, return false if the test is over
+func (c *simClient) pause(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(c.delay):
+		return true
+	}
+}
+
+// connect, join the room and play games until ctx is done
+func (c *simClient) run(ctx context.Context) {
+	conn, err := net.DialTimeout("tcp", serverAddr, requestTimeout)
+	if err != nil {
+		c.addError("unable to connect: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	c.conn = conn
+	c.reader = netutils.NewMsgReader(conn, 0)
+
+	if !c.join(ctx) {
+		return
+	}
+
+	for ctx.Err() == nil {
+		var ok bool
+		if c.host {
+			ok = c.hostGame(ctx)
+		} else {
+			ok = c.awaitGame(ctx)
+		}
+
+		if !ok || !c.play(ctx) {
+			break
+		}
+	}
+
+	netutils.SendMsg(conn, "leave")
+}
+
+// the host creates the room, the others join it
+func (c *simClient) join(ctx context.Context) bool {
+	if c.host {
+		resp, err := c.request("join " + c.name + " new")
+		if err != nil || resp != "ok" {
+			c.addError("unable to create a room: %v%s", err, resp)
+			return false
+		}
+
+		resp, err = c.request("get room")
+		if err != nil {
+			c.addError("unable to get the room: %v", err)
+			return false
+		}
+
+		c.room.id, _, _ = strings.Cut(resp, " ")
+		close(c.room.created)
+
+		resp, err = c.request("set max-players " + strconv.Itoa(c.room.size))
+		if err != nil || resp != "ok" {
+			c.addError("unable to set the room size: %v%s", err, resp)
+			return false
+		}
+
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.room.created:
+	}
+
+	resp, err := c.request("join " + c.name + " " + c.room.id)
+	if err != nil || resp != "ok" {
+		c.addError("unable to join a room: %v%s", err, resp)
+		return false
+	}
+
+	return true
+}
+
+// wait until everybody joined and is ready, then start a game
+func (c *simClient) hostGame(ctx context.Context) bool {
+	for c.pause(ctx) {
+		resp, err := c.request("get room")
+		if err != nil {
+			c.addError("connection: %v", err)
+			return false
+		}
+
+		// the players are listed after the sixth line as "<name> <y/n>"
+		lines := strings.Split(resp, "\n")
+		if len(lines) < 6+c.room.size || strings.Contains(strings.Join(lines[6:], "\n"), " n") {
+			continue
+		}
+
+		resp, err = c.request("start")
+		if err != nil {
+			c.addError("connection: %v", err)
+			return false
+		} else if resp != "ok" {
+			c.refused++
+			continue
+		}
+
+		c.room.gameStarted()
+		return true
+	}
+
+	return false
+}
+
+// get ready and wait for the host to start the game
+func (c *simClient) awaitGame(ctx context.Context) bool {
+	started := c.room.nextGame()
+
+	resp, err := c.request("ready y")
+	if err != nil {
+		c.addError("connection: %v", err)
+		return false
+	} else if resp != "ok" {
+		c.addError("unable to get ready: %s", resp)
+		return false
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-started:
+		return true
+	}
+}
+
+// play random legal moves until the game is over
+func (c *simClient) play(ctx context.Context) bool {
+	for c.pause(ctx) {
+		resp, err := c.request("get update")
+		if err != nil {
+			c.addError("connection: %v", err)
+			return false
+		}
+
+		lines := strings.Split(resp, "\n")
+		if len(lines) != 5 {
+			c.addError("invalid update: %q", resp)
+			return false
+		}
+
+		if lines[0] != "u" {
+			if c.host {
+				c.games++
+			}
+			return true
+		}
+
+		// the number of cards placed by the last player and the rank they claimed, or 0 and the current rank
+		amount, rankName, _ := strings.Cut(lines[2], " ")
+		rank, err := cardutils.RankByName(rankName)
+		if err != nil {
+			c.addError("invalid update: %q", resp)
+			return false
+		}
+		if amount != "0" {
+			rank = cardutils.NextRank(rank)
+		}
+
+		if lines[1] == "y" {
+			err = c.placeCards(rank)
+		} else if amount != "0" && lines[3] == "0" && c.rng.Float64() < doubtChance {
+			err = c.doubt()
+		}
+
+		if err != nil {
+			c.addError("connection: %v", err)
+			return false
+		}
+	}
+
+	return false
+}
+
+// place 1 to 4 cards, of the current rank unless the player bluffs
+func (c *simClient) placeCards(rank cardutils.Rank) error {
+	resp, err := c.request("get cards")
+	if err != nil {
+		return err
+	}
+
+	if resp == "" {
+		// the player is waiting for the others to accept their last cards
+		return nil
+	}
+	hand := strings.Split(resp, ",")
+
+	honest := make([]string, 0)
+	for _, name := range hand {
+		if card, err := cardutils.CardByName(name); err == nil && card.Rank == rank {
+			honest = append(honest, name)
+		}
+	}
+
+	choice := honest
+	if len(honest) == 0 || c.rng.Float64() < bluffChance {
+		c.rng.Shuffle(len(hand), func(i, j int) { hand[i], hand[j] = hand[j], hand[i] })
+		choice = hand[:min(len(hand), 1+c.rng.Intn(4))]
+	}
+
+	resp, err = c.request("place " + strings.Join(choice, ","))
+	if err != nil {
+		return err
+	} else if resp != "ok" {
+		c.refused++
+	}
+
+	return nil
+}
+
+func (c *simClient) doubt() error {
+	resp, err := c.request("dubito")
+	if err != nil {
+		return err
+	} else if resp != "right" && !strings.HasPrefix(resp, "wrong") {
+		// someone else placed or doubted first
+		c.refused++
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// loadtest simulates many players against a running server and reports how the server coped.
+//
+// Each room is created by one of the simulated players, the others join it, and they play random games
+// until the test is over. Run the server with -metrics to also get its goroutines and memory.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// configuration of the test, shared by the clients
+var serverAddr string
+var seed int64
+var delay time.Duration
+var bluffChance float64
+var doubtChance float64
+
+func main() {
+	var address, metricsURL string
+	var port, clients, roomSize int
+	var duration time.Duration
+
+	flag.StringVar(&address, "a", "localhost", "address of the server")
+	flag.IntVar(&port, "p", 9876, "port of the server")
+	flag.IntVar(&clients, "c", 200, "number of simulated players")
+	flag.IntVar(&roomSize, "r", 4, "players in each room, from 2 to 6")
+	flag.DurationVar(&duration, "d", time.Minute, "duration of the test")
+	flag.DurationVar(&delay, "delay", 100*time.Millisecond, "time each player waits between two requests, lower it to play faster")
+	flag.Float64Var(&bluffChance, "bluff", 0.3, "chance that a player bluffs when they have cards of the current rank")
+	flag.Float64Var(&doubtChance, "doubt", 0.1, "chance that a player doubts the last play each time they look at the table")
+	flag.Int64Var(&seed, "seed", time.Now().UnixNano(), "seed of the random moves")
+	flag.StringVar(&metricsURL, "metrics", "", "URL of the metrics of the server, for example \"http://localhost:9100/metrics\", to report its goroutines and memory")
+	flag.Parse()
+
+	if clients < roomSize || roomSize < 2 || roomSize > 6 {
+		fmt.Fprintln(os.Stderr, "the rooms must have 2 to 6 players and there must be enough players to fill one")
+		os.Exit(2)
+	}
+
+	serverAddr = net.JoinHostPort(address, strconv.Itoa(port))
+
+	var serverBefore processState
+	if metricsURL != "" {
+		var err error
+		serverBefore, err = serverState(metricsURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "unable to read the metrics of the server: "+err.Error())
+			os.Exit(1)
+		}
+	}
+	localBefore := localState()
+
+	// the players left over by the last full room are not used
+	sims := make([]*simClient, 0, clients)
+	for i := 0; i+roomSize <= clients; i += roomSize {
+		room := newSimRoom(roomSize)
+		for j := 0; j < roomSize; j++ {
+			sims = append(sims, newSimClient(i+j, room, j == 0))
+		}
+	}
+
+	fmt.Printf("playing with %d clients against %s for %s\n", len(sims), serverAddr, duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for _, c := range sims {
+		wg.Add(1)
+		go func(c *simClient) {
+			defer wg.Done()
+			c.run(ctx)
+		}(c)
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+
+	fmt.Println("")
+	printReport(os.Stdout, sims, elapsed)
+	fmt.Println("")
+
+	printStateChange(os.Stdout, "load tester", localBefore, localState())
+
+	if metricsURL != "" {
+		// let the server clean up the connections
+		time.Sleep(time.Second)
+
+		serverAfter, err := serverState(metricsURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "unable to read the metrics of the server: "+err.Error())
+			os.Exit(1)
+		}
+
+		printStateChange(os.Stdout, "server", serverBefore, serverAfter)
+	}
+}
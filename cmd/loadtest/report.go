@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// state of a process, to spot goroutine and memory leaks
+type processState struct {
+	goroutines int
+	heapBytes  uint64
+}
+
+func localState() processState {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return processState{goroutines: runtime.NumGoroutine(), heapBytes: mem.HeapAlloc}
+}
+
+// read the state of the server from its metrics
+func serverState(url string) (processState, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return processState{}, err
+	}
+	defer resp.Body.Close()
+
+	var ps processState
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "go_goroutines":
+			ps.goroutines, err = strconv.Atoi(value)
+		case "go_memstats_heap_alloc_bytes":
+			ps.heapBytes, err = strconv.ParseUint(value, 10, 64)
+		}
+		if err != nil {
+			return processState{}, err
+		}
+	}
+
+	return ps, scanner.Err()
+}
+
+func formatBytes(b uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(b)/(1<<20))
+}
+
+func printStateChange(w io.Writer, what string, before, after processState) {
+	fmt.Fprintf(w, "%s: %d -> %d goroutines, %s -> %s heap\n", what, before.goroutines, after.goroutines, formatBytes(before.heapBytes), formatBytes(after.heapBytes))
+}
+
+// return the p-th percentile of sorted, 0 < p <= 1
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	return sorted[i]
+}
+
+// print the results of the clients
+func printReport(w io.Writer, clients []*simClient, elapsed time.Duration) {
+	latencies := make(map[string][]time.Duration)
+	errors := make(map[string]int)
+	requests, refused, errorCount, games := 0, 0, 0, 0
+
+	for _, c := range clients {
+		for kind, l := range c.latencies {
+			latencies[kind] = append(latencies[kind], l...)
+			requests += len(l)
+		}
+
+		for e, n := range c.errors {
+			errors[e] += n
+			errorCount += n
+		}
+
+		refused += c.refused
+		games += c.games
+	}
+
+	rooms := make(map[*simRoom]bool)
+	for _, c := range clients {
+		rooms[c.room] = true
+	}
+
+	fmt.Fprintf(w, "%d clients in %d rooms for %s\n", len(clients), len(rooms), elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "%d requests (%.1f per second), %d refused by the rules of the game\n", requests, float64(requests)/elapsed.Seconds(), refused)
+	fmt.Fprintf(w, "%d games played until the end (%.1f per minute)\n", games, float64(games)/elapsed.Minutes())
+	fmt.Fprintf(w, "%d errors\n", errorCount)
+
+	descriptions := make([]string, 0, len(errors))
+	for e := range errors {
+		descriptions = append(descriptions, e)
+	}
+	sort.Slice(descriptions, func(i, j int) bool {
+		return errors[descriptions[i]] > errors[descriptions[j]]
+	})
+	for _, e := range descriptions {
+		fmt.Fprintf(w, "  %6d  %s\n", errors[e], e)
+	}
+
+	kinds := make([]string, 0, len(latencies))
+	for kind := range latencies {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "%-16s %8s %10s %10s %10s %10s\n", "request", "count", "p50", "p90", "p99", "max")
+	for _, kind := range kinds {
+		l := latencies[kind]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+
+		fmt.Fprintf(w, "%-16s %8d %10s %10s %10s %10s\n", kind, len(l),
+			percentile(l, 0.5).Round(time.Microsecond), percentile(l, 0.9).Round(time.Microsecond),
+			percentile(l, 0.99).Round(time.Microsecond), l[len(l)-1].Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const accountsFile = "accounts.json"
+
+const maxNameLength = 16
+const minPasswordLength = 4
+
+type account struct {
+	Name string `json:"name"`
+	// bcrypt hash, which also contains the salt
+	PasswordHash []byte `json:"passwordHash"`
+}
+
+// registered accounts, the key is the lowercase name
+var accounts map[string]*account = make(map[string]*account)
+
+// mutex for accounts
+var accountsMutex sync.Mutex
+
+func loadAccounts() error {
+	accountsMutex.Lock()
+	defer accountsMutex.Unlock()
+
+	list := make([]*account, 0)
+	err := loadJSON(dataPath(accountsFile), &list)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range list {
+		accounts[strings.ToLower(a.Name)] = a
+	}
+
+	return nil
+}
+
+// accountsMutex must be held by the caller
+func saveAccounts() error {
+	list := make([]*account, 0, len(accounts))
+	for _, a := range accounts {
+		list = append(list, a)
+	}
+
+	return saveJSON(dataPath(accountsFile), list)
+}
+
+// check whether name can be used as a player name
+// names are sent as comma-separated lists, so only a safe subset of characters is allowed
+func validateName(name string) error {
+	if len(name) == 0 || len(name) > maxNameLength {
+		return fmt.Errorf("the name must be 1 to %d characters long", maxNameLength)
+	}
+
+	for _, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && !isDigit && r != '_' && r != '-' && r != '.' {
+			return fmt.Errorf("the name can only contain letters, digits, '_', '-' and '.'")
+		}
+	}
+
+	return nil
+}
+
+// return true if name belongs to a registered account
+func isRegistered(name string) bool {
+	accountsMutex.Lock()
+	defer accountsMutex.Unlock()
+
+	_, ok := accounts[strings.ToLower(name)]
+	return ok
+}
+
+func registerAccount(name, password string) error {
+	err := validateName(name)
+	if err != nil {
+		return err
+	}
+
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("the password must be at least %d characters long", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	accountsMutex.Lock()
+	defer accountsMutex.Unlock()
+
+	key := strings.ToLower(name)
+	if _, ok := accounts[key]; ok {
+		return fmt.Errorf("the name is already registered")
+	}
+
+	accounts[key] = &account{Name: name, PasswordHash: hash}
+
+	err = saveAccounts()
+	if err != nil {
+		delete(accounts, key)
+		return err
+	}
+
+	return nil
+}
+
+// check the credentials and return the account name as it was registered
+func login(name, password string) (string, error) {
+	accountsMutex.Lock()
+	a, ok := accounts[strings.ToLower(name)]
+	accountsMutex.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("wrong name or password")
+	}
+
+	err := bcrypt.CompareHashAndPassword(a.PasswordHash, []byte(password))
+	if err != nil {
+		return "", fmt.Errorf("wrong name or password")
+	}
+
+	return a.Name, nil
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+)
+
+type adminPlayer struct {
+	Name      string `json:"name"`
+	Addr      string `json:"addr"`
+	Account   bool   `json:"account"`
+	Room      string `json:"room"`
+	Spectator bool   `json:"spectator"`
+	Ready     bool   `json:"ready"`
+	Cards     int    `json:"cards"`
+}
+
+type adminRoom struct {
+	ID         string        `json:"id"`
+	Private    bool          `json:"private"`
+	InviteCode string        `json:"invite_code,omitempty"`
+	Variant    string        `json:"variant"`
+	MinPlayers int           `json:"min_players"`
+	MaxPlayers int           `json:"max_players"`
+	TurnTime   int           `json:"turn_time"`
+	Ranked     bool          `json:"ranked"`
+	Started    bool          `json:"started"`
+	Over       bool          `json:"over"`
+	GameID     int           `json:"game_id"`
+	Players    []adminPlayer `json:"players"` // the first one is the host
+	Spectators []adminPlayer `json:"spectators"`
+}
+
+// full state of the game of a room, including the hidden cards
+type adminGame struct {
+	adminRoom
+	Hands           map[string][]string `json:"hands"`
+	Turn            string              `json:"turn"`
+	CurrentRank     string              `json:"current_rank"`
+	Pile            []string            `json:"pile"`
+	LastPlacer      string              `json:"last_placer"`
+	LastPlacedCards []string            `json:"last_placed_cards"`
+	LastClaimRank   string              `json:"last_claim_rank"`
+	Standings       []string            `json:"standings"`
+	PendingFinisher string              `json:"pending_finisher"`
+}
+
+// body of the POST requests, each request uses some of the fields
+type adminRequest struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	Text string `json:"text"`
+	Room string `json:"room"`
+}
+
+func newAdminPlayer(p *player, spectator bool) adminPlayer {
+	return adminPlayer{
+		Name:      p.name,
+		Addr:      p.conn.RemoteAddr().String(),
+		Account:   p.account,
+		Room:      p.room.id,
+		Spectator: spectator,
+		Ready:     p.ready,
+		Cards:     p.cards.Len(),
+	}
+}
+
+// globVarMutex must be held by the caller
+func newAdminRoom(r *room) adminRoom {
+	ar := adminRoom{
+		ID:         r.id,
+		Private:    r.private,
+		InviteCode: r.inviteCode,
+		Variant:    r.getVariantName(),
+		MinPlayers: r.minPlayers,
+		MaxPlayers: r.maxPlayers,
+		TurnTime:   r.turnTime,
+		Ranked:     r.ranked,
+		Started:    r.gameStarted,
+		Over:       r.gameOver,
+		GameID:     r.gameID,
+		Players:    make([]adminPlayer, len(r.players)),
+		Spectators: make([]adminPlayer, len(r.spectators)),
+	}
+
+	for i, p := range r.players {
+		ar.Players[i] = newAdminPlayer(p, false)
+	}
+
+	for i, s := range r.spectators {
+		ar.Spectators[i] = newAdminPlayer(s, true)
+	}
+
+	return ar
+}
+
+// globVarMutex must be held by the caller
+func newAdminGame(r *room) adminGame {
+	ag := adminGame{
+		adminRoom:       newAdminRoom(r),
+		Hands:           make(map[string][]string),
+		CurrentRank:     cardutils.RankToString(r.currentRank),
+		Pile:            cardutils.CardsToString(r.placedCards),
+		LastPlacedCards: cardutils.CardsToString(r.lastPlacedCards),
+		Standings:       make([]string, len(r.standings)),
+	}
+
+	for _, p := range r.players {
+		ag.Hands[p.name] = cardutils.CardsToString(p.cards.Sort(cardutils.ByRank))
+	}
+
+	if r.gameStarted && len(r.players) > 0 {
+		ag.Turn = r.players[r.currentTurn].name
+	}
+
+	if r.lastPlacer != nil {
+		ag.LastPlacer = r.lastPlacer.name
+		ag.LastClaimRank = cardutils.RankToString(r.lastClaimRank)
+	}
+
+	for i, p := range r.standings {
+		ag.Standings[i] = p.name
+	}
+
+	if r.pendingFinisher != nil {
+		ag.PendingFinisher = r.pendingFinisher.name
+	}
+
+	return ag
+}
+
+// return the rooms sorted by id
+// globVarMutex must be held by the caller
+func sortedRooms() []*room {
+	sorted := make([]*room, 0, len(rooms))
+	for _, r := range rooms {
+		sorted = append(sorted, r)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i].id)
+		b, _ := strconv.Atoi(sorted[j].id)
+		return a < b
+	})
+
+	return sorted
+}
+
+// close the connections of the players called name and of the connections from ip, either of them can be empty
+// return the number of closed connections
+// globVarMutex must be held by the caller
+func kickConnections(name, ip string) int {
+	kicked := 0
+
+	for conn, p := range openConns {
+		if (name != "" && p != nil && strings.EqualFold(p.name, name)) || (ip != "" && connIP(conn) == ip) {
+			if p != nil {
+				p.room.addChatMessage(serverChatName, p.name+" has been kicked by an administrator")
+			}
+
+			// the handler cleans up as soon as the connection is closed
+			conn.Close()
+			kicked++
+		}
+	}
+
+	return kicked
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// decode the body of a POST request, writing the error if there is one
+// the body must be sent as JSON, which browsers cannot do across sites without asking first
+func readAdminRequest(w http.ResponseWriter, req *http.Request) (adminRequest, bool) {
+	var ar adminRequest
+
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST"))
+		return ar, false
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("the body must be sent as application/json"))
+		return ar, false
+	}
+
+	err := json.NewDecoder(req.Body).Decode(&ar)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return ar, false
+	}
+
+	return ar, true
+}
+
+// reject the requests which do not come from the local machine, which come from a web page or which do not have the token
+// addr is the address the API listens to, requests for other hosts are sent by web pages which use DNS rebinding
+// token is the token the requests must have, empty if they do not need one
+func adminGuard(addr, token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			writeError(w, http.StatusForbidden, fmt.Errorf("the admin API is only available locally"))
+			return
+		}
+
+		if req.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, fmt.Errorf("the admin API cannot be used from web pages"))
+			return
+		}
+
+		if !strings.EqualFold(req.Host, addr) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("unknown host %q", req.Host))
+			return
+		}
+
+		if token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong token"))
+			return
+		}
+
+		slog.Info("admin request", "method", req.Method, "path", req.URL.Path)
+		h.ServeHTTP(w, req)
+	})
+}
+
+// the admin API has these endpoints, the POST requests take a JSON object as body, "{}" if they have no fields:
+//
+//	GET  /rooms                list the rooms and their players
+//	GET  /rooms/<id>           get the full state of the game of a room, including the hands
+//	POST /rooms/<id>/end       end the game of a room without recording it
+//	GET  /players              list the players and spectators of all the rooms
+//	POST /kick                 close the connections of a player ("name") or of an IP address ("ip")
+//	GET  /bans                 list the banned names and IP addresses
+//	POST /ban                  ban a name ("name") or an IP address ("ip") and kick them
+//	POST /unban                unban a name ("name") or an IP address ("ip")
+//	POST /announce             send a message ("text") to the chat of a room ("room") or of all the rooms
+//	POST /shutdown             shut the server down
+func adminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/rooms", func(w http.ResponseWriter, req *http.Request) {
+		globVarMutex.Lock()
+		defer globVarMutex.Unlock()
+
+		list := make([]adminRoom, 0, len(rooms))
+		for _, r := range sortedRooms() {
+			list = append(list, newAdminRoom(r))
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	})
+
+	mux.HandleFunc("/rooms/", func(w http.ResponseWriter, req *http.Request) {
+		id, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/rooms/"), "/")
+
+		// the body is read before taking the lock
+		if action == "end" {
+			if _, ok := readAdminRequest(w, req); !ok {
+				return
+			}
+		}
+
+		globVarMutex.Lock()
+		defer globVarMutex.Unlock()
+
+		r, ok := rooms[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown room"))
+			return
+		}
+
+		switch action {
+		case "":
+			writeJSON(w, http.StatusOK, newAdminGame(r))
+		case "end":
+			if !r.gameStarted || r.gameOver {
+				writeError(w, http.StatusConflict, fmt.Errorf("the game is not running"))
+			} else {
+				r.abortGame()
+				r.addChatMessage(serverChatName, "the game has been ended by an administrator")
+				writeJSON(w, http.StatusOK, newAdminGame(r))
+			}
+		default:
+			writeError(w, http.StatusNotFound, fmt.Errorf("unknown action"))
+		}
+	})
+
+	mux.HandleFunc("/players", func(w http.ResponseWriter, req *http.Request) {
+		globVarMutex.Lock()
+		defer globVarMutex.Unlock()
+
+		list := make([]adminPlayer, 0)
+		for _, r := range sortedRooms() {
+			ar := newAdminRoom(r)
+			list = append(list, ar.Players...)
+			list = append(list, ar.Spectators...)
+		}
+
+		writeJSON(w, http.StatusOK, list)
+	})
+
+	mux.HandleFunc("/kick", func(w http.ResponseWriter, req *http.Request) {
+		ar, ok := readAdminRequest(w, req)
+		if !ok {
+			return
+		}
+
+		if ar.Name == "" && ar.IP == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing name or ip"))
+			return
+		}
+
+		globVarMutex.Lock()
+		kicked := kickConnections(ar.Name, ar.IP)
+		globVarMutex.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]int{"kicked": kicked})
+	})
+
+	mux.HandleFunc("/bans", func(w http.ResponseWriter, req *http.Request) {
+		bansMutex.Lock()
+		defer bansMutex.Unlock()
+
+		writeJSON(w, http.StatusOK, getBanList())
+	})
+
+	mux.HandleFunc("/ban", func(w http.ResponseWriter, req *http.Request) {
+		ar, ok := readAdminRequest(w, req)
+		if !ok {
+			return
+		}
+
+		if ar.Name == "" && ar.IP == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing name or ip"))
+			return
+		}
+
+		setBanned(ar.Name, ar.IP, true)
+
+		globVarMutex.Lock()
+		kicked := kickConnections(ar.Name, ar.IP)
+		globVarMutex.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]int{"kicked": kicked})
+	})
+
+	mux.HandleFunc("/unban", func(w http.ResponseWriter, req *http.Request) {
+		ar, ok := readAdminRequest(w, req)
+		if !ok {
+			return
+		}
+
+		if ar.Name == "" && ar.IP == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("missing name or ip"))
+			return
+		}
+
+		setBanned(ar.Name, ar.IP, false)
+		writeJSON(w, http.StatusOK, map[string]string{})
+	})
+
+	mux.HandleFunc("/announce", func(w http.ResponseWriter, req *http.Request) {
+		ar, ok := readAdminRequest(w, req)
+		if !ok {
+			return
+		}
+
+		text := sanitizeChatText(ar.Text)
+		if text == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("the message is empty"))
+			return
+		}
+
+		globVarMutex.Lock()
+		defer globVarMutex.Unlock()
+
+		if ar.Room != "" {
+			r, ok := rooms[ar.Room]
+			if !ok {
+				writeError(w, http.StatusNotFound, fmt.Errorf("unknown room"))
+				return
+			}
+			r.addChatMessage(serverChatName, text)
+		} else {
+			for _, r := range rooms {
+				r.addChatMessage(serverChatName, text)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]string{})
+	})
+
+	mux.HandleFunc("/shutdown", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := readAdminRequest(w, req); !ok {
+			return
+		}
+
+		requestShutdown()
+		writeJSON(w, http.StatusOK, map[string]string{})
+	})
+
+	return mux
+}
+
+// return the HTTP server serving the admin API
+func newAdminServer(addr, token string) *http.Server {
+	return &http.Server{Addr: addr, Handler: adminGuard(addr, token, adminMux())}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminGuard(t *testing.T) {
+	newTestServer(t)
+
+	const addr = "127.0.0.1:9101"
+	h := adminGuard(addr, "secret", adminMux())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		remote      string
+		host        string
+		origin      string
+		token       string
+		contentType string
+		want        int
+	}{
+		{"list the bans", "GET", "/bans", "", "127.0.0.1:5000", addr, "", "secret", "", http.StatusOK},
+		{"kick nobody", "POST", "/kick", `{"name": "nobody"}`, "127.0.0.1:5000", addr, "", "secret", "application/json", http.StatusOK},
+		{"remote address", "GET", "/bans", "", "192.0.2.1:5000", addr, "", "secret", "", http.StatusForbidden},
+		{"web page", "GET", "/bans", "", "127.0.0.1:5000", addr, "http://example.com", "secret", "", http.StatusForbidden},
+		{"DNS rebinding", "GET", "/bans", "", "127.0.0.1:5000", "example.com:9101", "", "secret", "", http.StatusForbidden},
+		{"missing token", "GET", "/bans", "", "127.0.0.1:5000", addr, "", "", "", http.StatusUnauthorized},
+		{"wrong token", "GET", "/bans", "", "127.0.0.1:5000", addr, "", "guess", "", http.StatusUnauthorized},
+		{"plain text body", "POST", "/kick", `{"name": "nobody"}`, "127.0.0.1:5000", addr, "", "secret", "text/plain", http.StatusUnsupportedMediaType},
+		{"shutdown without JSON", "POST", "/shutdown", "", "127.0.0.1:5000", addr, "", "secret", "", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://"+tt.host+tt.path, strings.NewReader(tt.body))
+		req.RemoteAddr = tt.remote
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d (%s)", tt.name, rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const bansFile = "bans.json"
+
+type banList struct {
+	Names []string `json:"names"` // lowercase names
+	IPs   []string `json:"ips"`
+}
+
+// banned names and IP addresses
+var bannedNames map[string]bool = make(map[string]bool)
+var bannedIPs map[string]bool = make(map[string]bool)
+
+// mutex for bannedNames and bannedIPs
+var bansMutex sync.Mutex
+
+func loadBans() error {
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	var list banList
+	err := loadJSON(dataPath(bansFile), &list)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range list.Names {
+		bannedNames[strings.ToLower(name)] = true
+	}
+
+	for _, ip := range list.IPs {
+		bannedIPs[ip] = true
+	}
+
+	return nil
+}
+
+// bansMutex must be held by the caller
+func saveBans() {
+	err := saveJSON(dataPath(bansFile), getBanList())
+	if err != nil {
+		slog.Error("unable to save the bans", "err", err)
+	}
+}
+
+// bansMutex must be held by the caller
+func getBanList() banList {
+	list := banList{Names: make([]string, 0, len(bannedNames)), IPs: make([]string, 0, len(bannedIPs))}
+
+	for name := range bannedNames {
+		list.Names = append(list.Names, name)
+	}
+	sort.Strings(list.Names)
+
+	for ip := range bannedIPs {
+		list.IPs = append(list.IPs, ip)
+	}
+	sort.Strings(list.IPs)
+
+	return list
+}
+
+// ban or unban a name or an IP address, either of them can be empty
+func setBanned(name, ip string, banned bool) {
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	if name != "" {
+		if banned {
+			bannedNames[strings.ToLower(name)] = true
+		} else {
+			delete(bannedNames, strings.ToLower(name))
+		}
+	}
+
+	if ip != "" {
+		if banned {
+			bannedIPs[ip] = true
+		} else {
+			delete(bannedIPs, ip)
+		}
+	}
+
+	saveBans()
+}
+
+func isNameBanned(name string) bool {
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	return bannedNames[strings.ToLower(name)]
+}
+
+// return true if the IP address of the remote end of conn is banned
+func isConnBanned(conn net.Conn) bool {
+	bansMutex.Lock()
+	defer bansMutex.Unlock()
+
+	return bannedIPs[connIP(conn)]
+}
+
+// return the IP address of the remote end of conn
+func connIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+
+	return host
+}
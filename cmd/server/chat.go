@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// number of messages kept in the chat, older messages are dropped
+const chatHistoryLength = 100
+
+const maxChatMessageLength = 200
+
+// minimum time between two messages sent by the same connection
+const chatMinInterval = time.Second
+
+// name used for messages sent by the server itself, it can never be a player name
+const serverChatName = "*"
+
+type chatMessage struct {
+	id   int
+	from string
+	text string
+}
+
+// lowercase words which are hidden from the chat
+var filteredWords map[string]bool = make(map[string]bool)
+
+// load the words to filter, one per line, from the file at path
+func loadWordFilter(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" {
+			filteredWords[word] = true
+		}
+	}
+
+	return scanner.Err()
+}
+
+// replace the filtered words in text with asterisks
+func filterChatText(text string) string {
+	words := strings.Split(text, " ")
+
+	for i, w := range words {
+		// ignore punctuation around the word
+		trimmed := strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+
+		if filteredWords[strings.ToLower(trimmed)] {
+			words[i] = strings.Replace(w, trimmed, strings.Repeat("*", len([]rune(trimmed))), 1)
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// add a message to the chat of the room
+// globVarMutex must be held by the caller
+func (r *room) addChatMessage(from, text string) {
+	r.chatMessages = append(r.chatMessages, chatMessage{id: r.nextChatID, from: from, text: text})
+	r.nextChatID++
+
+	if len(r.chatMessages) > chatHistoryLength {
+		r.chatMessages = r.chatMessages[len(r.chatMessages)-chatHistoryLength:]
+	}
+}
+
+// clean up the text of a message sent by a player, the result is empty if there is nothing to send
+func sanitizeChatText(text string) string {
+	// messages are sent line by line
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if len(runes) > maxChatMessageLength {
+		text = string(runes[:maxChatMessageLength])
+	}
+
+	return filterChatText(text)
+}
+
+// the chat response message has a line for each message with an id greater than or equal to since, structured as follows:
+// [message id] [sender name] [text]
+//
+// the sender name is "*" for messages sent by the server
+// globVarMutex must be held by the caller
+func (r *room) chatResponse(since int) string {
+	lines := make([]string, 0)
+
+	for _, m := range r.chatMessages {
+		if m.id >= since {
+			lines = append(lines, strconv.Itoa(m.id)+" "+m.from+" "+m.text)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -1,60 +1,256 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
-	"strconv"
 )
 
-// return the specified argument position, -1 if it could not be found
-func getArgPos(argname string) (pos int) {
-	pos = -1
+// configuration of the server, it is read from the config file, the environment and the command line arguments, in this order
+type config struct {
+	Address     string `json:"address"`
+	Port        int    `json:"port"`
+	MinPlayers  int    `json:"min_players"` // default settings of the rooms
+	MaxPlayers  int    `json:"max_players"`
+	Variant     string `json:"variant"`
+	TurnTime    int    `json:"turn_time"`
+	Ranked      bool   `json:"ranked"`
+	DataDir     string `json:"data_dir"`
+	WordFilter  string `json:"word_filter"` // path of the file with the words to filter from the chat, empty if there is none
+	ServerName  string `json:"server_name"` // empty to use the host name
+	NoDiscovery bool   `json:"no_discovery"`
+	LogFile     string `json:"log_file"` // empty to log to the standard error
+	LogLevel    string `json:"log_level"`
+	LogFormat   string `json:"log_format"`
+	// the log file is rotated once it grows over LogMaxSize megabytes, keeping LogMaxBackups old files
+	LogMaxSize    int `json:"log_max_size"`
+	LogMaxBackups int `json:"log_max_backups"`
+	// address of the HTTP listener serving the metrics, empty to disable it
+	MetricsAddress string `json:"metrics_address"`
+	// address of the HTTP listener serving the admin API, empty to disable it, it must be a loopback address
+	AdminAddress string `json:"admin_address"`
+	// token which the requests to the admin API must send as "Authorization: Bearer <token>", empty to not require it
+	AdminToken string `json:"admin_token"`
+	// seconds the running games have to end once the server starts shutting down
+	ShutdownTimeout int `json:"shutdown_timeout"`
+	// seconds between the heartbeats of the clients and seconds of silence after which a connection is considered dead
+	HeartbeatInterval int `json:"heartbeat_interval"`
+	HeartbeatTimeout  int `json:"heartbeat_timeout"`
+}
+
+// environment variables which override the config file, by flag name
+var envVars map[string]string = map[string]string{
+	"a":                  "DUBITO_ADDRESS",
+	"p":                  "DUBITO_PORT",
+	"n":                  "DUBITO_MIN_PLAYERS",
+	"m":                  "DUBITO_MAX_PLAYERS",
+	"v":                  "DUBITO_VARIANT",
+	"t":                  "DUBITO_TURN_TIME",
+	"r":                  "DUBITO_RANKED",
+	"d":                  "DUBITO_DATA_DIR",
+	"w":                  "DUBITO_WORD_FILTER",
+	"s":                  "DUBITO_SERVER_NAME",
+	"D":                  "DUBITO_NO_DISCOVERY",
+	"l":                  "DUBITO_LOG_FILE",
+	"log-level":          "DUBITO_LOG_LEVEL",
+	"log-format":         "DUBITO_LOG_FORMAT",
+	"log-max-size":       "DUBITO_LOG_MAX_SIZE",
+	"log-max-backups":    "DUBITO_LOG_MAX_BACKUPS",
+	"metrics":            "DUBITO_METRICS_ADDRESS",
+	"admin":              "DUBITO_ADMIN_ADDRESS",
+	"admin-token":        "DUBITO_ADMIN_TOKEN",
+	"shutdown-timeout":   "DUBITO_SHUTDOWN_TIMEOUT",
+	"heartbeat-interval": "DUBITO_HEARTBEAT_INTERVAL",
+	"heartbeat-timeout":  "DUBITO_HEARTBEAT_TIMEOUT",
+	"c":                  "DUBITO_CONFIG",
+}
+
+func defaultConfig() config {
+	return config{
+		Port:       9876,
+		MinPlayers: minPlayersLimit,
+		MaxPlayers: 6,
+		Variant:    "classic",
+		DataDir:    ".",
+
+		LogLevel:      "info",
+		LogFormat:     "text",
+		LogMaxSize:    10,
+		LogMaxBackups: 5,
+
+		ShutdownTimeout: 60,
 
-	for i, a := range os.Args {
-		if a == argname {
-			pos = i
+		HeartbeatInterval: 5,
+		HeartbeatTimeout:  20,
+	}
+}
+
+// read the configuration, -h prints the usage and exits
+func loadConfig(args []string) (config, error) {
+	cfg := defaultConfig()
+	var configPath string
+
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	fs.StringVar(&configPath, "c", "", "path of the JSON config file")
+	fs.StringVar(&cfg.Address, "a", cfg.Address, "address to listen to, empty for all the addresses")
+	fs.IntVar(&cfg.Port, "p", cfg.Port, "port to listen to")
+	fs.IntVar(&cfg.MinPlayers, "n", cfg.MinPlayers, "default minimum number of players needed to start a game")
+	fs.IntVar(&cfg.MaxPlayers, "m", cfg.MaxPlayers, "default maximum number of players which can join a game")
+	fs.StringVar(&cfg.Variant, "v", cfg.Variant, "default variant, either \"classic\" or \"placement\"")
+	fs.IntVar(&cfg.TurnTime, "t", cfg.TurnTime, "default seconds a player has to place cards, 0 for no limit")
+	fs.BoolVar(&cfg.Ranked, "r", cfg.Ranked, "make the games of new rooms ranked by default")
+	fs.StringVar(&cfg.DataDir, "d", cfg.DataDir, "directory where persistent data is stored")
+	fs.StringVar(&cfg.WordFilter, "w", cfg.WordFilter, "file containing the words to hide from the chat, one per line")
+	fs.StringVar(&cfg.ServerName, "s", cfg.ServerName, "name shown to the players looking for servers on their network (default the host name)")
+	fs.BoolVar(&cfg.NoDiscovery, "D", cfg.NoDiscovery, "do not answer the players looking for servers on their network")
+	fs.StringVar(&cfg.LogFile, "l", cfg.LogFile, "file to append the log to, empty for the standard error")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "lowest level of the logged messages, one of \"debug\", \"info\", \"warn\" and \"error\"")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "format of the log, either \"text\" (logfmt) or \"json\"")
+	fs.IntVar(&cfg.LogMaxSize, "log-max-size", cfg.LogMaxSize, "megabytes after which the log file is rotated, 0 to never rotate it")
+	fs.IntVar(&cfg.LogMaxBackups, "log-max-backups", cfg.LogMaxBackups, "number of rotated log files to keep")
+	fs.IntVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "seconds the running games have to end once the server receives SIGINT or SIGTERM")
+	fs.IntVar(&cfg.HeartbeatInterval, "heartbeat-interval", cfg.HeartbeatInterval, "seconds between the heartbeats the clients are asked to send")
+	fs.IntVar(&cfg.HeartbeatTimeout, "heartbeat-timeout", cfg.HeartbeatTimeout, "seconds without messages after which a connection is closed")
+	fs.StringVar(&cfg.AdminAddress, "admin", cfg.AdminAddress, "loopback address of the HTTP listener serving the admin API, for example \"127.0.0.1:9101\", empty to disable it")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "token the requests to the admin API must send as \"Authorization: Bearer <token>\", better set in the config file or in the environment, which other users cannot read")
+	fs.StringVar(&cfg.MetricsAddress, "metrics", cfg.MetricsAddress, "address of the HTTP listener serving the metrics at /metrics, for example \":9100\", empty to disable it")
+
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "usage: server [options]")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "options override the environment variables, which override the config file:")
+		fs.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(out, "  -%s (%s)\n    \t%s", f.Name, envVars[f.Name], f.Usage)
+			if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+				fmt.Fprintf(out, " (default %s)", f.DefValue)
+			}
+			fmt.Fprintln(out, "")
+		})
+	}
+
+	fs.Parse(args)
+
+	// remember the arguments, since the config file and the environment are read afterwards
+	explicit := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	if path, ok := explicit["c"]; ok {
+		configPath = path
+	} else if path, ok := os.LookupEnv(envVars["c"]); ok {
+		configPath = path
+	}
+
+	if configPath != "" {
+		err := loadConfigFile(configPath, &cfg)
+		if err != nil {
+			return config{}, err
 		}
 	}
 
-	return
-}
+	for name, env := range envVars {
+		if value, ok := os.LookupEnv(env); ok && name != "c" {
+			if err := fs.Set(name, value); err != nil {
+				return config{}, fmt.Errorf("invalid value %q for %s: %w", value, env, err)
+			}
+		}
+	}
 
-func getArgMaxPlayers() (int, error) {
-	pos := getArgPos("-m")
+	for name, value := range explicit {
+		fs.Set(name, value)
+	}
 
-	if pos == -1 {
-		return 0, fmt.Errorf("no -m arg found")
+	if cfg.ServerName == "" {
+		name, err := os.Hostname()
+		if err != nil {
+			return config{}, err
+		}
+		cfg.ServerName = name
 	}
 
-	maxPlayers, err := strconv.Atoi(os.Args[pos+1])
+	return cfg, validateConfig(cfg)
+}
+
+// read the JSON config file at path into cfg, fields which are not in the file keep their value
+func loadConfigFile(path string, cfg *config) error {
+	f, err := os.Open(path)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+
+	err = dec.Decode(cfg)
+	if err == io.EOF {
+		// the file is empty
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
 	}
 
-	return maxPlayers, nil
+	return nil
 }
 
-func getListenAddress() (string, error) {
-	pos := getArgPos("-a")
+func validateConfig(cfg config) error {
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		return fmt.Errorf("the port must be between 1 and 65535")
+	}
 
-	if pos == -1 {
-		return "", fmt.Errorf("no -a arg found")
+	if cfg.MinPlayers < minPlayersLimit || cfg.MaxPlayers > maxPlayersLimit {
+		return fmt.Errorf("the number of players must be between %d and %d", minPlayersLimit, maxPlayersLimit)
+	} else if cfg.MinPlayers > cfg.MaxPlayers {
+		return fmt.Errorf("the minimum number of players cannot be greater than the maximum")
 	}
 
-	return os.Args[pos+1], nil
-}
+	if cfg.Variant != "classic" && cfg.Variant != "placement" {
+		return fmt.Errorf("unknown variant: %s", cfg.Variant)
+	}
 
-func getListenPort() (uint16, error) {
-	pos := getArgPos("-p")
+	if cfg.TurnTime < 0 || cfg.TurnTime > maxTurnTime {
+		return fmt.Errorf("the turn time must be between 0 (no limit) and %d seconds", maxTurnTime)
+	}
 
-	if pos == -1 {
-		return 0, fmt.Errorf("no -p arg found")
+	if _, err := parseLogLevel(cfg.LogLevel); err != nil {
+		return err
 	}
 
-	port, err := strconv.Atoi(os.Args[pos+1])
-	if err != nil {
-		return 0, err
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		return fmt.Errorf("unknown log format: %s", cfg.LogFormat)
+	}
+
+	if cfg.LogMaxSize < 0 || cfg.LogMaxBackups < 0 {
+		return fmt.Errorf("the size and the number of the log files cannot be negative")
+	}
+
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("the shutdown timeout cannot be negative")
+	}
+
+	if cfg.HeartbeatInterval < 1 || cfg.HeartbeatTimeout <= cfg.HeartbeatInterval {
+		return fmt.Errorf("the heartbeat interval must be at least 1 second and shorter than the heartbeat timeout")
+	}
+
+	if cfg.AdminAddress != "" {
+		host, _, err := net.SplitHostPort(cfg.AdminAddress)
+		if err != nil {
+			return fmt.Errorf("invalid admin address: %w", err)
+		}
+
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("the admin API can only listen to a loopback address, such as 127.0.0.1")
+		}
+	}
+
+	if cfg.DataDir == "" {
+		return fmt.Errorf("the data directory cannot be empty")
 	}
 
-	return uint16(port), nil
+	return nil
 }
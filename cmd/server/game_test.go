@@ -0,0 +1,305 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPlaceChecks(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob")
+	alice, bob := players[0], players[1]
+	setHands(t, "ace spades,two spades", "three hearts")
+
+	bob.expect("place three hearts", "wrong turn")
+	alice.expect("place king clubs", "you don't have that card")
+	alice.expect("place ace spades,ace spades", "you placed ace spades more than once")
+	alice.expect("place ace spades,AS", "you placed ace spades more than once")
+	alice.expect("place ace", "invalid card: ace")
+	alice.expect("place ace spades,two spades,three spades,four spades,five spades", "too many cards")
+	alice.expect("place KC", "you don't have that card")
+	alice.expect("place AS,two of spades,3S,4S,5S", "too many cards")
+	alice.expect("dubito", "there is nothing to doubt")
+
+	alice.expect("place ace spades,two spades", "ok")
+	alice.expect("dubito", "you cannot doubt yourself")
+	bob.expect("get update", "u\ny\n2 ace\n0\n-1")
+}
+
+func TestDoubtRight(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob")
+	alice, bob := players[0], players[1]
+	setHands(t, "two spades,three spades", "four hearts")
+
+	// alice bluffs, she claims to place an ace
+	alice.expect("place two spades", "ok")
+	bob.expect("dubito", "right")
+
+	// alice takes the pile back and the turn goes to bob
+	alice.expect("get cards", "two spades,three spades")
+	alice.expect("get update", "u\nn\n0 two\n0\n-1")
+	bob.expect("get update", "u\ny\n0 two\n0\n-1")
+}
+
+func TestDoubtWrong(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob")
+	alice, bob := players[0], players[1]
+	setHands(t, "ace spades,three spades", "four hearts")
+
+	alice.expect("place ace spades", "ok")
+	bob.expect("dubito", "wrong\nace spades")
+
+	// bob takes the pile and alice plays again
+	bob.expect("get cards", "ace spades,four hearts")
+	alice.expect("get update", "u\ny\n0 two\n0\n-1")
+}
+
+func TestClaims(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob")
+	alice, bob := players[0], players[1]
+	setHands(t, "two spades,three spades,four spades", "four hearts,five hearts")
+
+	// the player who starts the pile claims any rank, the next one has to claim the rank after it
+	alice.expect("place two spades as seven", "ok")
+	bob.expect("get update", "u\ny\n1 seven\n0\n-1")
+	bob.expect("place four hearts as nine", "you have to claim eight")
+	bob.expect("place four hearts as joker", "invalid rank: joker")
+	bob.expect("place four hearts as eight", "ok")
+
+	// the doubt checks the claimed rank, bob takes the pile and alice starts a new one
+	alice.expect("dubito", "right")
+	alice.expect("place three spades as king", "ok")
+	bob.expect("place five hearts", "ok")
+	alice.expect("get update", "u\ny\n1 ace\n0\n-1")
+}
+
+func TestTable(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob")
+	alice, bob := players[0], players[1]
+	setHands(t, "two spades,three spades,four spades", "four hearts,five hearts,six hearts")
+
+	alice.expect("get table", "0\nalice 3,bob 3")
+	alice.expect("place two spades,three spades as two", "ok")
+	bob.expect("place four hearts as three", "ok")
+	bob.expect("get table", "3\nalice 1,bob 2\np alice 2 two\np bob 1 three")
+
+	// bob bluffed, so he takes the pile
+	alice.expect("dubito", "right")
+	alice.expect("get table", "0\nalice 1,bob 5\np alice 2 two\np bob 1 three\nd alice right bob 3")
+
+	other := connect(t)
+	other.expect("get table", "join or spectate the game first")
+}
+
+func TestClassicGame(t *testing.T) {
+	newTestServer(t)
+
+	alice := connect(t)
+	alice.expect("register alice secret", "ok")
+	bob := connect(t)
+	bob.expect("register bob secret", "ok")
+
+	alice.expect("join", "ok")
+	bob.expect("join", "ok")
+	bob.expect("ready y", "ok")
+	alice.expect("start", "ok")
+	setHands(t, "ace spades", "two hearts,queen clubs")
+
+	// alice places her last card, she finishes once bob accepts it
+	alice.expect("place ace spades", "ok")
+	alice.expect("get update", "u\nn\n1 ace\n0\n-1")
+	bob.expect("place two hearts", "ok")
+
+	alice.expect("get update", "y\nn\n1 two\n1\n-1")
+	bob.expect("get update", "n\nn\n1 two\n2\n-1")
+	bob.expect("get standings", "1 alice\n2 bob")
+	bob.expect("place queen clubs", "the game is not running")
+
+	// only the players who logged in have statistics
+	stats := connect(t)
+	if s := stats.request("get stats alice"); !strings.HasPrefix(s, "alice\n1 1\n0 0\n0 0\n") {
+		t.Errorf("unexpected statistics: %q", s)
+	}
+	stats.expect("get leaderboard", "alice 1 1\nbob 1 0")
+	stats.expect("get stats carol", "unknown player")
+
+	// the host can start another game
+	bob.expect("ready y", "ok")
+	alice.expect("start", "ok")
+	alice.expect("get update", "u\ny\n0 ace\n0\n-1")
+}
+
+func TestStatsSavedDuringGame(t *testing.T) {
+	newTestServer(t)
+
+	alice := connect(t)
+	alice.expect("register alice secret", "ok")
+	bob := connect(t)
+	bob.expect("register bob secret", "ok")
+
+	alice.expect("join", "ok")
+	bob.expect("join", "ok")
+	bob.expect("ready y", "ok")
+	alice.expect("start", "ok")
+	setHands(t, "two spades,three spades", "four hearts")
+
+	// starting the game does not write to the disk while the rooms are locked
+	if _, err := os.Stat(dataPath(statsFile)); err == nil {
+		t.Error("the statistics have been saved as soon as the game started")
+	}
+
+	// the bluff is saved before the game ends, such as when the server exits
+	alice.expect("place two spades", "ok")
+	bob.expect("dubito", "right")
+	flushStats()
+
+	saved := make([]*playerStats, 0)
+	if err := loadJSON(dataPath(statsFile), &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("%d players saved, want 2", len(saved))
+	}
+
+	for _, s := range saved {
+		if s.Name == "alice" && (s.BluffsAttempted != 1 || s.BluffsCaught != 1) {
+			t.Errorf("alice has %d bluffs attempted and %d caught, want 1 and 1", s.BluffsAttempted, s.BluffsCaught)
+		}
+		if s.Name == "bob" && s.DoubtsRight != 1 {
+			t.Errorf("bob has %d doubts right, want 1", s.DoubtsRight)
+		}
+	}
+}
+
+func TestPlacementGame(t *testing.T) {
+	newTestServer(t)
+
+	players := joinPlayers(t, "alice", "bob", "carol")
+	alice, bob, carol := players[0], players[1], players[2]
+	alice.expect("set variant placement", "ok")
+	for _, p := range players[1:] {
+		p.expect("ready y", "ok")
+	}
+	alice.expect("start", "ok")
+	setHands(t, "ace spades", "two hearts", "three clubs,king clubs")
+
+	alice.expect("place ace spades", "ok")
+	bob.expect("place two hearts", "ok")
+
+	// alice finished first, the game goes on for the others
+	bob.expect("get standings", "1 alice")
+	carol.expect("get update", "u\ny\n1 two\n0\n-1")
+
+	// bob finishes when carol accepts his play, which leaves carol alone
+	carol.expect("place three clubs", "ok")
+	carol.expect("place king clubs", "the game is not running")
+	alice.expect("get update", "y\nn\n1 three\n1\n-1")
+	bob.expect("get update", "n\nn\n1 three\n2\n-1")
+	carol.expect("get update", "n\nn\n1 three\n3\n-1")
+	carol.expect("get standings", "1 alice\n2 bob\n3 carol")
+}
+
+func TestDisconnectDuringGame(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob", "carol")
+	alice, bob, carol := players[0], players[1], players[2]
+	setHands(t, "ace spades", "two hearts", "three clubs")
+
+	alice.expect("place ace spades", "ok")
+
+	// bob vanishes while it is his turn, the turn moves to carol
+	bob.conn.Close()
+	waitFor(t, "bob to be removed", func() bool {
+		return len(rooms[defaultRoomID].players) == 2
+	})
+
+	alice.expect("get players", "alice,carol")
+	carol.expect("get update", "u\ny\n1 ace\n0\n-1")
+}
+
+func TestLeaveEndsGame(t *testing.T) {
+	newTestServer(t)
+
+	alice := connect(t)
+	alice.expect("register alice secret", "ok")
+	bob := connect(t)
+	bob.expect("register bob secret", "ok")
+
+	alice.expect("join", "ok")
+	alice.expect("set ranked y", "ok")
+	bob.expect("join", "ok")
+	bob.expect("ready y", "ok")
+	alice.expect("start", "ok")
+	setHands(t, "ace spades,two spades", "three hearts")
+
+	// bob leaves while the game is running, alice is the only player left so she wins and he is placed last
+	bob.conn.Write([]byte("leave\000"))
+	bob.expectClosed()
+	waitFor(t, "bob to be removed", func() bool {
+		return len(rooms[defaultRoomID].players) == 1
+	})
+
+	alice.expect("get update", "y\nn\n0 ace\n1\n-1")
+	alice.expect("get standings", "1 alice\n2 bob")
+	alice.expect("get ratings", "alice 1516 16")
+}
+
+func TestUnrankedRoom(t *testing.T) {
+	newTestServer(t)
+
+	// the default room was created before, so only the new room is ranked
+	defaultRanked = true
+	connect(t).expect("join carol new", "ok")
+	connect(t).expect("get rooms", "1 - 0 6 n n\n2 carol 1 6 n y")
+
+	alice := connect(t)
+	alice.expect("register alice secret", "ok")
+	bob := connect(t)
+	bob.expect("register bob secret", "ok")
+
+	alice.expect("join", "ok")
+	alice.expect("set ranked y", "ok")
+	alice.expect("set ranked n", "ok")
+	bob.expect("join", "ok")
+	bob.expect("ready y", "ok")
+	alice.expect("start", "ok")
+	setHands(t, "ace spades", "two hearts")
+
+	alice.expect("place ace spades", "ok")
+	alice.expect("get ratings", "alice 1500 0\nbob 1500 0")
+}
+
+func TestPendingFinisherLeaves(t *testing.T) {
+	newTestServer(t)
+
+	players := startGame(t, "alice", "bob", "carol")
+	alice, bob, carol := players[0], players[1], players[2]
+	setHands(t, "ace spades", "two hearts,king clubs", "three clubs")
+
+	// alice places her last card and leaves before anyone accepts it, nobody can doubt of it anymore
+	alice.expect("place ace spades", "ok")
+	alice.conn.Close()
+	waitFor(t, "alice to be removed", func() bool {
+		return len(rooms[defaultRoomID].players) == 2
+	})
+
+	bob.expect("get update", "u\ny\n0 two\n0\n-1")
+	bob.expect("dubito", "there is nothing to doubt")
+
+	// the game goes on until a player finishes, alice is placed last
+	bob.expect("place two hearts", "ok")
+	carol.expect("place three clubs", "ok")
+	bob.expect("place king clubs", "ok")
+	carol.expect("get standings", "1 carol\n2 bob\n3 alice")
+}
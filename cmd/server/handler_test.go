@@ -0,0 +1,218 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInvalidRequests(t *testing.T) {
+	newTestServer(t)
+	c := connect(t)
+
+	tests := []struct {
+		req  string
+		want string
+	}{
+		{" ", "empty request"},
+		{"foo", "unknown request"},
+		{"get", "usage: get <what> [argument]"},
+		{"get nothing", "unknown request"},
+		{"get chat x", "usage: get chat [first message id]"},
+		{"get stats", "usage: get stats <name>"},
+		{"set variant", "usage: set <setting> <value>"},
+		{"ready", "usage: ready <y/n>"},
+		{"start now", "usage: start"},
+		{"place", "usage: place <card>[,<card>...] [as <rank>]"},
+		{"login alice", "usage: login <name> <password>"},
+		{"chat " + strings.Repeat("x", maxRequestLength), "the request is too long"},
+	}
+
+	for _, tt := range tests {
+		c.expect(tt.req, tt.want)
+	}
+
+	// the connection is still usable
+	c.expect("ping", "pong 5 20")
+}
+
+func TestRequestsNeedJoining(t *testing.T) {
+	newTestServer(t)
+	c := connect(t)
+
+	for _, req := range []string{"ready y", "place ace spades", "dubito", "leave", "get players", "get room", "get cards", "get update"} {
+		c.expect(req, "join the game first")
+	}
+
+	c.expect("chat hello", "join or spectate the game first")
+	c.expect("get chat", "join or spectate the game first")
+	c.expect("start", "only the host can do that")
+	c.expect("set variant placement", "only the host can do that")
+	c.expect("kick bob", "only the host can do that")
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	newTestServer(t)
+
+	c := connect(t)
+	c.expect("register bob pw", "the password must be at least 4 characters long")
+	c.expect("register bob secret", "ok")
+	c.expect("register bob secret", "the name is already registered")
+
+	guest := connect(t)
+	guest.expect("join bob", "the name belongs to an account, log in to use it")
+	guest.expect("login bob wrong", "wrong name or password")
+	guest.expect("login bob secret", "ok")
+	guest.expect("join alice", "you are logged in as bob")
+
+	// the account name is used if the name is missing
+	guest.expect("join", "ok")
+	guest.expect("get players", "bob")
+	guest.expect("login bob secret", "you already joined")
+}
+
+func TestJoin(t *testing.T) {
+	newTestServer(t)
+
+	alice := connect(t)
+	alice.expect("join al!ce", "the name can only contain letters, digits, '_', '-' and '.'")
+	alice.expect("join alice", "ok")
+	alice.expect("join alice", "you already are in a room")
+
+	bob := connect(t)
+	bob.expect("join ALICE", "the name is already taken")
+	bob.expect("join bob", "ok")
+
+	alice.expect("get players", "alice,bob")
+	alice.expect("get max-players", "6")
+	alice.expect("get room", "1 -\n2 6\nclassic\n0\nn\nn\nalice y\nbob n")
+	alice.expect("get ratings", "alice - -\nbob - -")
+
+	alice.expect("set max-players 2", "ok")
+	connect(t).expect("join carol", "the game is full")
+}
+
+func TestRooms(t *testing.T) {
+	newTestServer(t)
+
+	joinPlayers(t, "alice")
+
+	bob := connect(t)
+	bob.expect("join bob new", "ok")
+	bob.expect("get players", "bob")
+	if room := bob.request("get room"); !strings.HasPrefix(room, "2 -\n") {
+		t.Errorf("bob is not in room 2: %q", room)
+	}
+
+	carol := connect(t)
+	carol.expect("join carol new-private secret", "ok")
+	room := carol.request("get room")
+	header, _, _ := strings.Cut(room, "\n")
+	id, code, _ := strings.Cut(header, " ")
+	if id != "3" || len(code) != inviteCodeLength {
+		t.Fatalf("unexpected private room: %q", header)
+	}
+
+	// private rooms are not listed
+	connect(t).expect("get rooms", "1 alice 1 6 n n\n2 bob 1 6 n n")
+
+	dave := connect(t)
+	dave.expect("join dave 3", "the room is private, a password or an invite code is needed")
+	dave.expect("join dave 3 nope", "wrong password or invite code")
+	dave.expect("join dave 42", "unknown room")
+	dave.expect("join dave 3 secret", "ok")
+
+	connect(t).expect("join erin "+code, "ok")
+	carol.expect("get players", "carol,dave,erin")
+}
+
+func TestSpectate(t *testing.T) {
+	newTestServer(t)
+
+	players := joinPlayers(t, "alice", "bob")
+
+	s := connect(t)
+	s.expect("spectate carol", "ok")
+	s.expect("chat hi", "ok")
+	s.expect("ready y", "join the game first")
+	s.expect("place ace spades", "join the game first")
+
+	players[0].expect("get chat 0", "0 carol hi")
+	players[0].expect("get players", "alice,bob")
+}
+
+func TestChatModeration(t *testing.T) {
+	newTestServer(t)
+
+	players := joinPlayers(t, "alice", "bob", "carol")
+	alice, bob, carol := players[0], players[1], players[2]
+
+	alice.expect("chat hello", "ok")
+	alice.expect("chat again", "you are sending messages too fast")
+	bob.expect("get chat", "0 alice hello")
+
+	bob.expect("mute alice", "only the host can do that")
+	alice.expect("mute alice", "you cannot do that to yourself")
+	alice.expect("mute dave", "unknown player")
+
+	alice.expect("mute bob", "ok")
+	bob.expect("chat hi", "you are muted")
+	alice.expect("unmute bob", "ok")
+	bob.expect("chat hi", "ok")
+	bob.expect("get chat 1", "1 * bob has been muted by the host\n2 * bob is no longer muted\n3 bob hi")
+
+	alice.expect("kick carol", "ok")
+	carol.expectClosed()
+	waitFor(t, "carol to be removed", func() bool {
+		return len(rooms[defaultRoomID].players) == 2
+	})
+	alice.expect("get players", "alice,bob")
+}
+
+func TestSettingsAndStart(t *testing.T) {
+	newTestServer(t)
+
+	alice := connect(t)
+	alice.expect("join alice", "ok")
+	alice.expect("start", "at least 2 players are needed")
+
+	bob := connect(t)
+	bob.expect("join bob", "ok")
+	bob.expect("set variant placement", "only the host can do that")
+
+	alice.expect("set variant poker", "unknown variant: poker")
+	alice.expect("set colour red", "unknown setting: colour")
+	alice.expect("set turn-time 30", "ok")
+	alice.expect("set variant placement", "ok")
+	alice.expect("set ranked maybe", "usage: set ranked <y/n>")
+	alice.expect("set ranked y", "ok")
+	alice.expect("start", "bob is not ready")
+
+	bob.expect("get cards", "the game has not started")
+	bob.expect("ready maybe", "usage: ready <y/n>")
+	bob.expect("ready y", "ok")
+	bob.expect("get room", "1 -\n2 6\nplacement\n30\ny\nn\nalice y\nbob y")
+
+	alice.expect("start", "ok")
+	alice.expect("start", "the game already started")
+	alice.expect("set variant classic", "the game already started")
+	bob.expect("ready n", "the game already started")
+
+	if cards := strings.Split(bob.request("get cards"), ","); len(cards) != 26 {
+		t.Errorf("bob got %d cards, want 26", len(cards))
+	}
+}
+
+func TestLeave(t *testing.T) {
+	newTestServer(t)
+
+	players := joinPlayers(t, "alice", "bob")
+
+	// leaving closes the connection without a response
+	players[1].conn.Write([]byte("leave\000"))
+	players[1].expectClosed()
+
+	waitFor(t, "bob to leave", func() bool {
+		return len(rooms[defaultRoomID].players) == 1
+	})
+	players[0].expect("get players", "alice")
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/netutils"
+)
+
+// longest time a test waits for the server
+const testTimeout = 5 * time.Second
+
+func TestMain(m *testing.M) {
+	// the tests check the responses, the log would only hide the failures
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	os.Exit(m.Run())
+}
+
+// reset the state of the server, so that each test starts with an empty room 1 and no accounts
+// the tests share the global state of the server, so they cannot run in parallel
+func newTestServer(t *testing.T) {
+	t.Helper()
+
+	dataDir = t.TempDir()
+
+	accountsMutex.Lock()
+	accounts = make(map[string]*account)
+	accountsMutex.Unlock()
+
+	statsMutex.Lock()
+	stats = make(map[string]*playerStats)
+	if statsSaveTimer != nil {
+		statsSaveTimer.Stop()
+		statsSaveTimer = nil
+	}
+	statsMutex.Unlock()
+
+	ratingsMutex.Lock()
+	ratings = make(map[string]*playerRating)
+	ratingsMutex.Unlock()
+
+	bansMutex.Lock()
+	bannedNames = make(map[string]bool)
+	bannedIPs = make(map[string]bool)
+	bansMutex.Unlock()
+
+	globVarMutex.Lock()
+	defaultMinPlayers = minPlayersLimit
+	defaultMaxPlayers = 6
+	defaultFullPlacement = false
+	defaultTurnTime = 0
+	defaultRanked = false
+	shuttingDown = false
+	rooms = make(map[string]*room)
+	lastRoomID = 0
+	lastGameID = 0
+	connectedPlayers = 0
+	openConns = make(map[net.Conn]*player)
+	newRoom(false, "")
+	globVarMutex.Unlock()
+
+	t.Cleanup(func() {
+		globVarMutex.Lock()
+		for conn := range openConns {
+			conn.Close()
+		}
+		globVarMutex.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			handlers.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(testTimeout):
+			t.Error("some handlers did not end")
+		}
+	})
+}
+
+// a scripted client connected to the server through an in-memory connection
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *netutils.MsgReader
+}
+
+// connect a new client to the server started by newTestServer
+func connect(t *testing.T) *testClient {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	acceptConn(serverConn)
+
+	c := &testClient{t: t, conn: clientConn, reader: netutils.NewMsgReader(clientConn, 0)}
+	t.Cleanup(func() { c.conn.Close() })
+
+	return c
+}
+
+// send a request and return its response, failing the test if there is none
+func (c *testClient) request(req string) string {
+	c.t.Helper()
+
+	c.conn.SetDeadline(time.Now().Add(testTimeout))
+
+	err := netutils.SendMsg(c.conn, req)
+	if err != nil {
+		c.t.Fatalf("unable to send %q: %v", req, err)
+	}
+
+	resp, err := c.reader.Recv()
+	if err != nil {
+		c.t.Fatalf("no response to %q: %v", req, err)
+	}
+
+	return resp
+}
+
+// send a request and check its response
+func (c *testClient) expect(req, want string) {
+	c.t.Helper()
+
+	got := c.request(req)
+	if got != want {
+		c.t.Errorf("%q: got %q, want %q", req, got, want)
+	}
+}
+
+// check that the server closed the connection
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+
+	c.conn.SetDeadline(time.Now().Add(testTimeout))
+
+	_, err := c.reader.Recv()
+	if err != io.EOF {
+		c.t.Errorf("the connection is still open: %v", err)
+	}
+}
+
+// connect a client for each name and join room 1, the first one is the host and the others are ready
+func joinPlayers(t *testing.T, names ...string) []*testClient {
+	t.Helper()
+
+	clients := make([]*testClient, len(names))
+	for i, name := range names {
+		clients[i] = connect(t)
+		clients[i].expect("join "+name, "ok")
+		if i > 0 {
+			clients[i].expect("ready y", "ok")
+		}
+	}
+
+	return clients
+}
+
+// join room 1 and start a game, the first player has the first turn
+func startGame(t *testing.T, names ...string) []*testClient {
+	t.Helper()
+
+	clients := joinPlayers(t, names...)
+	clients[0].expect("start", "ok")
+
+	return clients
+}
+
+// replace the cards dealt to the players of room 1, in the order they joined, so that the game is predictable
+func setHands(t *testing.T, hands ...string) {
+	t.Helper()
+
+	globVarMutex.Lock()
+	defer globVarMutex.Unlock()
+
+	r := rooms[defaultRoomID]
+	if len(hands) != len(r.players) {
+		t.Fatalf("%d hands for %d players", len(hands), len(r.players))
+	}
+
+	for i, h := range hands {
+		r.players[i].cards = cardutils.NewHand(parseTestCards(t, h)...)
+	}
+}
+
+// parse cards written as in the requests, such as "ace spades,two hearts"
+func parseTestCards(t *testing.T, s string) []cardutils.Card {
+	t.Helper()
+
+	cards := make([]cardutils.Card, 0)
+	for _, name := range strings.Split(s, ",") {
+		c, err := cardutils.CardByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cards = append(cards, c)
+	}
+
+	return cards
+}
+
+// wait until cond, which is called while holding globVarMutex, is true
+// the server cleans up a closed connection on its own, so its effects are not immediate
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for {
+		globVarMutex.Lock()
+		ok := cond()
+		globVarMutex.Unlock()
+
+		if ok {
+			return
+		} else if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// the clients send "ping" every heartbeatInterval, a connection which sends nothing for heartbeatTimeout is closed
+var heartbeatInterval time.Duration = 5 * time.Second
+var heartbeatTimeout time.Duration = 20 * time.Second
+
+// the response to "ping" is structured as follows:
+// pong [seconds between the heartbeats] [seconds after which the connection is closed]
+func pongResponse() string {
+	return "pong " + strconv.Itoa(int(heartbeatInterval.Seconds())) + " " + strconv.Itoa(int(heartbeatTimeout.Seconds()))
+}
+
+// return true if err was caused by a connection which stayed silent for too long
+func isTimeout(err error) bool {
+	return errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// file which is rotated once it grows over maxSize bytes
+// the old files are renamed to path.1, path.2 and so on, up to maxBackups, the oldest one is removed
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+
+	err := rf.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	rf.file = f
+	rf.size = info.Size()
+	return nil
+}
+
+// rf.mutex must be held by the caller
+func (rf *rotatingFile) rotate() error {
+	err := rf.file.Close()
+	if err != nil {
+		return err
+	}
+
+	for i := rf.maxBackups; i > 0; i-- {
+		older := rf.path + "." + strconv.Itoa(i)
+		newer := rf.path
+		if i > 1 {
+			newer += "." + strconv.Itoa(i-1)
+		}
+
+		if i == rf.maxBackups {
+			os.Remove(older)
+		}
+		os.Rename(newer, older)
+	}
+
+	if rf.maxBackups == 0 {
+		os.Remove(rf.path)
+	}
+
+	return rf.open()
+}
+
+func (rf *rotatingFile) Write(b []byte) (int, error) {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(b)) > rf.maxSize {
+		err := rf.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(b)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) Close() error {
+	rf.mutex.Lock()
+	defer rf.mutex.Unlock()
+
+	return rf.file.Close()
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	switch level {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+
+	return 0, fmt.Errorf("unknown log level: %s", level)
+}
+
+// set up the default logger as specified by the configuration
+// the returned closer, if not nil, closes the log file
+func setupLogging(cfg config) (io.Closer, error) {
+	level, err := parseLogLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer
+	if cfg.LogFile != "" {
+		rf, err := openRotatingFile(cfg.LogFile, int64(cfg.LogMaxSize)*1024*1024, cfg.LogMaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out, closer = rf, rf
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if cfg.LogFormat == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return closer, nil
+}
+
+// return true if the hidden data of the game, such as the cards of the players, can be logged
+func debugLogging() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
+
+// return the request in a form which can be logged
+// passwords are always hidden, while cards are only shown at debug level since the other players must not know them
+func redactRequest(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	switch fields[0] {
+	case "register", "login":
+		return fields[0] + " ***"
+	case "join", "spectate":
+		// the fourth field is the password or the invite code of a private room
+		if len(fields) > 3 {
+			return strings.Join(fields[:3], " ") + " ***"
+		}
+	case "place":
+		if !debugLogging() {
+			// the claimed rank is public, only the cards are hidden
+			args, claim := fields[1:], ""
+			if n := len(args); n >= 2 && args[n-2] == "as" {
+				args, claim = args[:n-2], " as "+args[n-1]
+			}
+
+			return "place [" + strconv.Itoa(len(strings.Split(strings.Join(args, " "), ","))) + " cards]" + claim
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// return a logger which adds the room and the game to each line
+func (r *room) logger() *slog.Logger {
+	return slog.With("room", r.id, "game", r.gameID)
+}
+
+// return a logger which adds the room, the game and the player to each line
+func (p *player) logger() *slog.Logger {
+	return p.room.logger().With("player", p.name, "addr", p.conn.RemoteAddr().String())
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,64 +21,32 @@ import (
 )
 
 type player struct {
-	conn  net.Conn
-	name  string
-	cards []cardutils.Card
+	conn    net.Conn
+	name    string
+	account bool  // true if the player logged in
+	ready   bool  // true if the player is ready to start the game
+	room    *room // room which the player joined or is spectating
+	cards   cardutils.Hand
 }
 
 var connectedPlayers int
-var joinedPlayers []*player = make([]*player, 0)
-var currentTurn int = 0
-var currentRank cardutils.Rank
-var placedCards []cardutils.Card
-var lastPlacedCards []cardutils.Card
+
+// open connections and the player who joined or is spectating on each of them, nil if there is none
+var openConns map[net.Conn]*player = make(map[net.Conn]*player)
 
 // mutex for global variables
 var globVarMutex sync.Mutex
 
-func getPlayerByConn(conn net.Conn) (int, *player) {
-	repeat := false
-
-	if conn == nil {
-		return -1, nil
-	}
-
-	for {
-		for i, p := range joinedPlayers {
-			if p == nil {
-				// a race condition happened
-				repeat = true
-				break
-			}
-
-			if p.conn.RemoteAddr() == conn.RemoteAddr() {
-				return i, p
-			}
-		}
-
-		if !repeat {
-			break
-		} else {
-			repeat = false
+// return the joined player or spectator of the room called name, regardless of the letter case, or nil if there is none
+func (r *room) getPlayerByName(name string) *player {
+	for _, p := range r.players {
+		if strings.EqualFold(p.name, name) {
+			return p
 		}
 	}
 
-	return -1, nil
-}
-
-func fmtPlayerName(p *player) string {
-	return p.name + " (" + p.conn.RemoteAddr().String() + ")"
-}
-
-// check if the current turn is the player's turn
-func checkPlayerTurn(p *player) bool {
-	return joinedPlayers[currentTurn].conn.RemoteAddr() == p.conn.RemoteAddr()
-}
-
-// returns the winner or nil if the game is not over yet
-func checkWin() *player {
-	for _, p := range joinedPlayers {
-		if len(p.cards) == 0 {
+	for _, p := range r.spectators {
+		if strings.EqualFold(p.name, name) {
 			return p
 		}
 	}
@@ -80,36 +54,42 @@ func checkWin() *player {
 	return nil
 }
 
-// check if player has cards
-// the cards should not be duplicated
-func checkPlayerHasCards(p *player, cards []cardutils.Card) bool {
-	cardsFound := make(map[cardutils.Card]bool)
+// check whether a connection which is logged in as loggedInAs (empty if not logged in) can use name to join or spectate
+// logged in players always use their account name, which is returned
+// names are unique across all the rooms
+func checkNewName(name, loggedInAs string) (string, error) {
+	if loggedInAs != "" {
+		if name != "" && !strings.EqualFold(name, loggedInAs) {
+			return "", fmt.Errorf("you are logged in as " + loggedInAs)
+		}
+		name = loggedInAs
+	} else {
+		err := validateName(name)
+		if err != nil {
+			return "", err
+		}
 
-	// add cards
-	for _, c := range cards {
-		cardsFound[c] = false
+		if isRegistered(name) {
+			return "", fmt.Errorf("the name belongs to an account, log in to use it")
+		}
 	}
 
-	for _, c := range cards {
-		for _, pc := range p.cards {
-			if c == pc {
-				cardsFound[c] = true
-			}
-		}
+	if isNameBanned(name) {
+		return "", fmt.Errorf("you are banned from this server")
 	}
 
-	for _, found := range cardsFound {
-		if !found {
-			return false
+	for _, r := range rooms {
+		if r.getPlayerByName(name) != nil {
+			return "", fmt.Errorf("the name is already taken")
 		}
 	}
 
-	return true
+	return name, nil
 }
 
 // return true if all the cards match the rank
-func checkLastPlacedCards(rank cardutils.Rank) bool {
-	for _, c := range lastPlacedCards {
+func checkCardsRank(cards []cardutils.Card, rank cardutils.Rank) bool {
+	for _, c := range cards {
 		if c.Rank != rank {
 			return false
 		}
@@ -118,296 +98,655 @@ func checkLastPlacedCards(rank cardutils.Rank) bool {
 	return true
 }
 
-func handler(conn net.Conn, maxPlayers int, addPlayer chan<- *player, removePlayer chan<- *player) {
-	log.Println("a player connected (IP: " + conn.RemoteAddr().String() + ")")
+func handler(conn net.Conn) {
 	hasJoined := false
-	var p *player     // read this only if the player has joined
-	var indexInJP int // index in joinedPlayers
+	var p *player         // read this only if the player has joined
+	var loggedInAs string // account name, empty if the player did not log in
+	var spectator *player // not nil if the connection is spectating a game
+	var lastChatTime time.Time
+
+	// return the logger of the connection, which adds the room, the game and the player once the connection joins or spectates
+	// globVarMutex must be held by the caller
+	logger := func() *slog.Logger {
+		if hasJoined {
+			return p.logger()
+		} else if spectator != nil {
+			return spectator.logger()
+		}
+
+		return slog.With("addr", conn.RemoteAddr().String())
+	}
+
+	globVarMutex.Lock()
+	logger().Info("a player connected")
+	globVarMutex.Unlock()
+
+	// error which ended the connection, nil if the player left
+	var connErr error
+
+	reader := netutils.NewMsgReader(conn, maxRequestLength)
+	limiter := newRateLimiter()
 
 	// remove player when handler ends
-	defer func() { connectedPlayers--; removePlayer <- p }()
+	defer func() {
+		defer handlers.Done()
+		conn.Close()
+
+		globVarMutex.Lock()
+		if connErr == io.EOF || (shuttingDown && errors.Is(connErr, net.ErrClosed)) {
+			logger().Info("the connection has been closed")
+		} else if isTimeout(connErr) {
+			logger().Info("the connection timed out")
+			if hasJoined {
+				p.room.addChatMessage(serverChatName, p.name+" lost the connection")
+			}
+		} else if connErr != nil {
+			logger().Warn("an error occurred while reading a message", "err", connErr)
+		}
+
+		connectedPlayers--
+		delete(openConns, conn)
+		if hasJoined {
+			p.room.removePlayer(p)
+		}
+		if spectator != nil {
+			spectator.room.removeSpectator(spectator)
+		}
+		globVarMutex.Unlock()
+	}()
 
-msgLoop:
 	for {
-		msg, err := netutils.RecvMsg(conn)
-		if err != nil {
-			if err == io.EOF {
-				// connection closed
-				log.Println("the connection to " + conn.RemoteAddr().String() + " has been closed")
-			} else {
-				log.Println("an error occurred while reading a message: " + err.Error())
+		// a client which vanished without closing the connection would block the handler forever,
+		// the deadline also covers sending the response
+		conn.SetDeadline(time.Now().Add(heartbeatTimeout))
+
+		msg, err := reader.Recv()
+		if errors.Is(err, netutils.ErrMsgTooLong) {
+			netutils.SendMsg(conn, "the request is too long")
+			recordProtocolError()
+			continue
+		} else if err != nil {
+			connErr = err
+			if isTimeout(err) {
+				recordConnTimeout()
+			} else if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				recordProtocolError()
 			}
 			break
 		}
 
-		log.Println(conn.RemoteAddr().String() + " made a request: \"" + msg + "\"")
+		if !limiter.allow() {
+			netutils.SendMsg(conn, "too many requests, slow down")
+			recordRateLimited()
+			continue
+		}
+
+		requestStart := time.Now()
 
-		fields := strings.Fields(msg)
+		fields, err := parseRequest(msg)
+		if err != nil {
+			globVarMutex.Lock()
+			logger().Warn("invalid request", "request", redactRequest(fields), "err", err)
+			globVarMutex.Unlock()
 
-		if len(fields) == 0 {
+			netutils.SendMsg(conn, err.Error())
+			recordProtocolError()
+			recordRequest("", time.Since(requestStart))
 			continue
 		}
 
 		globVarMutex.Lock()
 
-		switch fields[0] {
-		case "join":
-			if !hasJoined {
-				if len(joinedPlayers) == maxPlayers {
-					netutils.SendMsg(conn, "the game is full")
-					break msgLoop
+		// the clients poll the state of the game, do not flood the log with it
+		if fields[0] == "get" || fields[0] == "ping" {
+			logger().Debug("request", "request", redactRequest(fields))
+		} else {
+			logger().Info("request", "request", redactRequest(fields))
+		}
+
+		// the connection is either a joined player or a spectator of r, r is nil if it is neither
+		var r *room
+		if hasJoined {
+			r = p.room
+		} else if spectator != nil {
+			r = spectator.room
+		}
+
+		// true if the connection has to be closed after the request
+		leave, panicked := false, false
+
+		// a bug triggered by a request must not crash the whole server, only its connection is closed
+		// globVarMutex is held when a panic is recovered, since it is only released while hashing passwords and sleeping
+		func() {
+			defer func() {
+				if v := recover(); v != nil {
+					panicked = true
+					recordPanic()
+					logger().Error("a request caused a panic", "request", redactRequest(fields), "panic", v, "stack", string(debug.Stack()))
+				}
+			}()
+
+			switch fields[0] {
+			case "register", "login":
+				if hasJoined {
+					netutils.SendMsg(conn, "you already joined")
 				} else {
-					tmpPlayer := new(player)
-					tmpPlayer.conn = conn
-					tmpPlayer.name = fields[1]
-					addPlayer <- tmpPlayer
-
-					// wait for the player to be added to joinedPlayers
-					for {
-						index, foundPlayer := getPlayerByConn(conn)
-						if foundPlayer == nil {
-							continue
-						}
+					name := fields[1]
+					password := strings.Join(fields[2:], " ")
+
+					// hashing is slow, do not hold the lock meanwhile
+					globVarMutex.Unlock()
+					if fields[0] == "register" {
+						err = registerAccount(name, password)
+					} else {
+						name, err = login(name, password)
+					}
+					globVarMutex.Lock()
 
-						p = foundPlayer
-						indexInJP = index
-						hasJoined = true
-						break
+					// registering also logs the player in
+					if err != nil {
+						netutils.SendMsg(conn, err.Error())
+					} else {
+						loggedInAs = name
+						netutils.SendMsg(conn, "ok")
+						logger().Info("logged in", "account", name)
 					}
+				}
+			case "join", "spectate":
+				// join <name> [room] [secret]
+				var name, roomArg, secret string
+				if len(fields) > 1 {
+					name = fields[1]
+				}
+				if len(fields) > 2 {
+					roomArg = fields[2]
+				}
+				if len(fields) > 3 {
+					secret = fields[3]
+				}
+
+				if r != nil {
+					netutils.SendMsg(conn, "you already are in a room")
+				} else if shuttingDown {
+					netutils.SendMsg(conn, "the server is shutting down")
+				} else if name, err = checkNewName(name, loggedInAs); err != nil {
+					netutils.SendMsg(conn, err.Error())
+				} else if r, err = findRoom(roomArg, secret); err != nil {
+					netutils.SendMsg(conn, err.Error())
+				} else if fields[0] == "join" && (len(r.players) >= r.maxPlayers || r.gameStarted) {
+					netutils.SendMsg(conn, "the game is full")
+				} else if fields[0] == "join" {
+					p = &player{conn: conn, name: name, account: loggedInAs != ""}
+					r.addPlayer(p)
+					openConns[conn] = p
+					hasJoined = true
+					netutils.SendMsg(conn, "ok")
+
+					logger().Info("player joined")
+				} else {
+					spectator = &player{conn: conn, name: name, account: loggedInAs != ""}
+					r.addSpectator(spectator)
+					openConns[conn] = spectator
 					netutils.SendMsg(conn, "ok")
 
-					log.Println("player " + fmtPlayerName(p) + " joined")
+					logger().Info("player is spectating")
+				}
+			case "chat":
+				sender := spectator
+				if hasJoined {
+					sender = p
 				}
-			}
-		case "get":
-			if hasJoined {
-				switch fields[1] {
-				case "players":
-					var jpStr string
-					for _, p := range joinedPlayers {
-						jpStr += p.name + ","
-					}
-					jpStr = strings.TrimSuffix(jpStr, ",")
-					netutils.SendMsg(conn, jpStr)
 
-				case "max-players":
-					netutils.SendMsg(conn, strconv.Itoa(maxPlayers))
+				if sender == nil {
+					netutils.SendMsg(conn, "join or spectate the game first")
+				} else if r.mutedPlayers[strings.ToLower(sender.name)] {
+					netutils.SendMsg(conn, "you are muted")
+				} else if time.Since(lastChatTime) < chatMinInterval {
+					netutils.SendMsg(conn, "you are sending messages too fast")
+				} else if text := sanitizeChatText(strings.Join(fields[1:], " ")); text == "" {
+					netutils.SendMsg(conn, "the message is empty")
+				} else {
+					lastChatTime = time.Now()
+					r.addChatMessage(sender.name, text)
+					netutils.SendMsg(conn, "ok")
+				}
+			case "mute", "unmute", "kick":
+				var target *player
+				if hasJoined && len(fields) > 1 {
+					target = r.getPlayerByName(fields[1])
+				}
 
-				case "cards":
-					for len(p.cards) == 0 {
-						// wait to get cards
-						time.Sleep(time.Millisecond * 100)
+				if !hasJoined || !r.isHost(p) {
+					netutils.SendMsg(conn, "only the host can do that")
+				} else if target == nil {
+					netutils.SendMsg(conn, "unknown player")
+				} else if target == p {
+					netutils.SendMsg(conn, "you cannot do that to yourself")
+				} else {
+					switch fields[0] {
+					case "mute":
+						r.mutedPlayers[strings.ToLower(target.name)] = true
+						r.addChatMessage(serverChatName, target.name+" has been muted by the host")
+					case "unmute":
+						delete(r.mutedPlayers, strings.ToLower(target.name))
+						r.addChatMessage(serverChatName, target.name+" is no longer muted")
+					case "kick":
+						// the handler of the target ends as soon as the connection is closed
+						target.conn.Close()
+						r.addChatMessage(serverChatName, target.name+" has been kicked by the host")
 					}
 
-					var cardsStr string
-					for _, c := range p.cards {
-						cardsStr += cardutils.CardToString(c) + ","
+					netutils.SendMsg(conn, "ok")
+					logger().Info("the host moderated the chat", "action", fields[0], "target", target.name)
+				}
+			case "set":
+				if !hasJoined || !r.isHost(p) {
+					netutils.SendMsg(conn, "only the host can do that")
+				} else if r.gameStarted && !r.gameOver {
+					netutils.SendMsg(conn, "the game already started")
+				} else if err := r.setSetting(fields[1], fields[2]); err != nil {
+					netutils.SendMsg(conn, err.Error())
+				} else {
+					netutils.SendMsg(conn, "ok")
+					logger().Info("the host changed a setting", "setting", fields[1], "value", fields[2])
+				}
+			case "ready":
+				if !hasJoined {
+					netutils.SendMsg(conn, "join the game first")
+				} else if r.gameStarted && !r.gameOver {
+					netutils.SendMsg(conn, "the game already started")
+				} else if fields[1] != "y" && fields[1] != "n" {
+					netutils.SendMsg(conn, "usage: ready <y/n>")
+				} else {
+					p.ready = fields[1] == "y"
+					netutils.SendMsg(conn, "ok")
+				}
+			case "start":
+				if !hasJoined || !r.isHost(p) {
+					netutils.SendMsg(conn, "only the host can do that")
+				} else if shuttingDown {
+					netutils.SendMsg(conn, "the server is shutting down")
+				} else if err := r.checkCanStart(); err != nil {
+					netutils.SendMsg(conn, err.Error())
+				} else {
+					r.startGame()
+					netutils.SendMsg(conn, "ok")
+				}
+			case "get":
+				if fields[1] == "chat" {
+					since := 0
+					if len(fields) > 2 {
+						since, _ = strconv.Atoi(fields[2])
 					}
-					cardsStr = strings.TrimSuffix(cardsStr, ",")
-					netutils.SendMsg(conn, cardsStr)
-
-				case "update":
-					// construct the update response string
-					var udResp string
 
-					// first line
-					winner := checkWin()
-					if winner == nil {
-						udResp += "u\n"
+					if r != nil {
+						netutils.SendMsg(conn, r.chatResponse(since))
 					} else {
-						if winner == p {
-							udResp = "y\nn\n1 ace"
-						} else {
-							udResp = "n\nn\n1 ace"
-						}
-						continue
+						netutils.SendMsg(conn, "join or spectate the game first")
 					}
-
-					if checkPlayerTurn(p) {
-						udResp += "y\n"
+				} else if fields[1] == "table" {
+					// spectators see the table too, but not the cards of the players
+					if r != nil {
+						netutils.SendMsg(conn, r.tableResponse())
+					} else {
+						netutils.SendMsg(conn, "join or spectate the game first")
+					}
+				} else if fields[1] == "stats" {
+					// statistics, leaderboard and public rooms are available without joining
+					if resp, ok := statsResponse(fields[2]); ok {
+						netutils.SendMsg(conn, resp)
 					} else {
-						udResp += "n\n"
+						netutils.SendMsg(conn, "unknown player")
 					}
+				} else if fields[1] == "leaderboard" {
+					netutils.SendMsg(conn, leaderboardResponse())
+				} else if fields[1] == "rooms" {
+					netutils.SendMsg(conn, roomsResponse())
+				} else if hasJoined {
+					switch fields[1] {
+					case "players":
+						var jpStr string
+						for _, p := range r.players {
+							jpStr += p.name + ","
+						}
+						jpStr = strings.TrimSuffix(jpStr, ",")
+						netutils.SendMsg(conn, jpStr)
+
+					case "room":
+						netutils.SendMsg(conn, r.roomResponse())
 
-					udResp += strconv.Itoa(len(lastPlacedCards)) + " " + cardutils.RankToString(currentRank)
+					case "ratings":
+						netutils.SendMsg(conn, ratingsResponse(r.players))
 
-				default:
-					log.Println("invalid request from " + fmtPlayerName(p) + ": \"" + msg + "\"")
+					case "max-players":
+						netutils.SendMsg(conn, strconv.Itoa(r.maxPlayers))
+
+					case "cards":
+						if !r.gameStarted {
+							netutils.SendMsg(conn, "the game has not started")
+						} else {
+							netutils.SendMsg(conn, strings.Join(cardutils.CardsToString(p.cards.Sort(cardutils.ByRank)), ","))
+						}
+
+					case "update":
+						// construct the update response string
+						var udResp string
+
+						// first and second line
+						if r.gameOver && !r.aborted && r.standings[0] == p {
+							udResp += "y\nn\n"
+						} else if r.gameOver {
+							udResp += "n\nn\n"
+						} else if r.checkPlayerTurn(p) {
+							udResp += "u\ny\n"
+						} else {
+							udResp += "u\nn\n"
+						}
+
+						// third line
+						if r.lastPlacer == nil {
+							udResp += "0 " + cardutils.RankToString(r.currentRank)
+						} else {
+							udResp += strconv.Itoa(len(r.lastPlacedCards)) + " " + cardutils.RankToString(r.lastClaimRank)
+						}
+
+						// fourth line
+						udResp += "\n" + strconv.Itoa(r.getPlacement(p))
+
+						// fifth line
+						udResp += "\n" + strconv.Itoa(shutdownCountdown())
+
+						netutils.SendMsg(conn, udResp)
+
+					case "standings":
+						netutils.SendMsg(conn, r.standingsResponse())
+					}
+				} else {
+					netutils.SendMsg(conn, "join the game first")
 				}
-			}
-		case "place":
-			if hasJoined {
-				if !checkPlayerTurn(p) {
+			case "place":
+				if !hasJoined {
+					netutils.SendMsg(conn, "join the game first")
+				} else if !r.gameStarted || r.gameOver {
+					netutils.SendMsg(conn, "the game is not running")
+				} else if !r.checkPlayerTurn(p) {
 					netutils.SendMsg(conn, "wrong turn")
+				} else if cards, claim, err := parsePlace(fields[1:]); err != nil {
+					logger().Warn("invalid cards placed", "err", err)
+					netutils.SendMsg(conn, err.Error())
+				} else if !p.cards.ContainsAll(cards...) {
+					netutils.SendMsg(conn, "you don't have that card")
+				} else if claim, err = r.checkClaim(claim); err != nil {
+					netutils.SendMsg(conn, err.Error())
 				} else {
-					cardsStr := strings.Split(strings.Join(fields[1:], " "), ",")
-					cards := make([]cardutils.Card, len(cardsStr))
-
-					// convert cardsStr into cards
-					for i, cs := range cardsStr {
-						card, err := cardutils.CardByName(cs)
-						if err != nil {
-							log.Println("invalid card placed: \"" + cs + "\"")
-							break
-						}
-						cards[i] = card
+					// by placing cards, the player accepts the play of the last one
+					if r.pendingFinisher != nil {
+						r.confirmFinisher()
 					}
 
-					// check number of cards
-					if len(cards) >= 1 && len(cards) <= 4 {
-						// check if the player have those cards
-						if checkPlayerHasCards(p, cards) {
-							// place the cards
-							placedCards = append(placedCards, cards...)
-							lastPlacedCards = cards
-							netutils.SendMsg(conn, "ok")
-							currentTurn++
-
-							if currentTurn >= len(joinedPlayers) {
-								currentTurn = 0
-							}
-						} else {
-							netutils.SendMsg(conn, "you don't have that card")
+					recordPlace(p, p.cards.Len(), !checkCardsRank(cards, claim))
+
+					// place the cards
+					p.cards.Remove(cards...)
+					r.placedCards = append(r.placedCards, cards...)
+					r.lastPlacedCards = cards
+					r.lastPlacer = p
+					r.lastClaimRank = claim
+					r.currentRank = cardutils.NextRank(claim)
+					r.addTableEvent(tableEvent{player: p.name, count: len(cards), claim: claim})
+					netutils.SendMsg(conn, "ok")
+
+					if p.cards.Len() == 0 {
+						r.pendingFinisher = p
+					}
+
+					// the game may have ended if the player accepted the last play of the second to last player
+					if !r.gameOver {
+						r.currentTurn = r.getNextTurn(r.currentTurn)
+					}
+				}
+			case "dubito":
+				if !hasJoined {
+					netutils.SendMsg(conn, "join the game first")
+				} else if !r.gameStarted || r.gameOver {
+					netutils.SendMsg(conn, "the game is not running")
+				} else if r.lastPlacer == nil {
+					netutils.SendMsg(conn, "there is nothing to doubt")
+				} else if r.lastPlacer == p {
+					netutils.SendMsg(conn, "you cannot doubt yourself")
+				} else if p.cards.Len() == 0 {
+					netutils.SendMsg(conn, "you finished the game")
+				} else {
+					// send "right" if last player lied, "wrong" otherwise
+					if checkCardsRank(r.lastPlacedCards, r.lastClaimRank) {
+						// last player didn't lie, the player who doubted takes the cards
+						response := "wrong\n" + strings.Join(cardutils.CardsToString(r.placedCards), ",")
+						netutils.SendMsg(conn, response)
+						p.cards.Add(r.placedCards...)
+						recordDoubt(p, r.lastPlacer, false)
+						r.addTableEvent(tableEvent{doubt: true, player: p.name, doubted: r.lastPlacer.name, taken: len(r.placedCards)})
+
+						// repeat the turn for the last player, or move on if they placed their last cards
+						if i := r.getPlayerIndex(r.lastPlacer); i != -1 {
+							r.currentTurn = i
+						}
+
+						if r.pendingFinisher != nil {
+							r.currentTurn = r.getNextTurn(r.currentTurn)
+							r.confirmFinisher()
 						}
 					} else {
-						netutils.SendMsg(conn, "too many cards")
+						// last player lied and takes the cards, if they placed their last cards they are back in the game
+						netutils.SendMsg(conn, "right")
+						r.lastPlacer.cards.Add(r.placedCards...)
+						recordDoubt(p, r.lastPlacer, true)
+						r.addTableEvent(tableEvent{doubt: true, player: p.name, right: true, doubted: r.lastPlacer.name, taken: len(r.placedCards)})
+						r.currentTurn = r.getPlayerIndex(p)
+						r.pendingFinisher = nil
 					}
+
+					r.placedCards = nil
+					r.lastPlacedCards = nil
+					r.lastPlacer = nil
 				}
-			}
-		case "dubito":
-			if hasJoined {
-				// send "right" if last player lied, "wrong" otherwise
-				if checkLastPlacedCards(currentRank) {
-					// last player didn't lie
-					response := "wrong\n" + strings.Join(cardutils.CardsToString(placedCards), ",")
-					netutils.SendMsg(conn, response)
-
-					// repeat the turn for the last player
-					currentTurn--
+			case "ping":
+				netutils.SendMsg(conn, pongResponse())
+			case "leave":
+				if hasJoined {
+					logger().Info("player left")
+					leave = true
 				} else {
-					// last player lied
-					netutils.SendMsg(conn, "right")
-					currentTurn = indexInJP
+					netutils.SendMsg(conn, "join the game first")
 				}
 			}
-		case "leave":
-			if hasJoined {
-				log.Println("player " + fmtPlayerName(p) + " left")
-				break msgLoop
-			}
-		default:
-			log.Println("invalid request from " + fmtPlayerName(p) + ": \"" + msg + "\"")
+		}()
+
+		if panicked {
+			netutils.SendMsg(conn, "internal server error")
+			leave = true
 		}
 
 		globVarMutex.Unlock()
+
+		recordRequest(fields[0], time.Since(requestStart))
+
+		if leave {
+			break
+		}
+	}
+}
+
+// start handling a new connection, unless it comes from a banned address
+func acceptConn(conn net.Conn) {
+	if isConnBanned(conn) {
+		slog.Info("refused a connection from a banned address", "addr", conn.RemoteAddr().String())
+		conn.Close()
+		return
 	}
+
+	globVarMutex.Lock()
+	connectedPlayers++
+	openConns[conn] = nil
+	globVarMutex.Unlock()
+
+	handlers.Add(1)
+	go handler(conn)
 }
 
-func giveCards(playersCount int) [][]cardutils.Card {
+func giveCards(playersCount int) []cardutils.Hand {
 	cardsEach := 52 / playersCount
-	givenCards := make(map[cardutils.Card]bool)
-	cards := make([][]cardutils.Card, playersCount)
-
-	for i := range cards {
-		playerCards := make([]cardutils.Card, cardsEach)
-
-		for j := range playerCards {
-			for {
-				suit := rand.Intn(4)
-				rank := rand.Intn(13) + 1
-				card := cardutils.Card{Suit: cardutils.Suit(suit), Rank: cardutils.Rank(rank)}
-
-				if !givenCards[card] {
-					playerCards[j] = card
-					givenCards[card] = true
-					break
-				}
+	var givenCards cardutils.Hand
+	hands := make([]cardutils.Hand, playersCount)
+
+	for i := range hands {
+		for hands[i].Len() < cardsEach {
+			suit := rand.Intn(4)
+			rank := rand.Intn(13) + 1
+			card := cardutils.Card{Suit: cardutils.Suit(suit), Rank: cardutils.Rank(rank)}
+
+			if !givenCards.Contains(card) {
+				hands[i].Add(card)
+				givenCards.Add(card)
 			}
 		}
-
-		cards[i] = playerCards
 	}
 
-	return cards
+	return hands
 }
 
 func main() {
-	lisAddr, err := getListenAddress()
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration: "+err.Error())
+		os.Exit(2)
+	}
+
+	logCloser, err := setupLogging(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unable to set up logging: "+err.Error())
+		os.Exit(1)
+	}
+	if logCloser != nil {
+		defer logCloser.Close()
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort(cfg.Address, strconv.Itoa(cfg.Port)))
 	if err != nil {
 		panic(err.Error())
 	}
 
-	lisPort, err := getListenPort()
+	defaultMinPlayers = cfg.MinPlayers
+	defaultMaxPlayers = cfg.MaxPlayers
+	defaultFullPlacement = cfg.Variant == "placement"
+	defaultTurnTime = cfg.TurnTime
+	defaultRanked = cfg.Ranked
+	heartbeatInterval = time.Duration(cfg.HeartbeatInterval) * time.Second
+	heartbeatTimeout = time.Duration(cfg.HeartbeatTimeout) * time.Second
+	dataDir = cfg.DataDir
+
+	err = os.MkdirAll(dataDir, 0700)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	lis, err := net.Listen("tcp", lisAddr+":"+strconv.Itoa(int(lisPort)))
+	err = loadAccounts()
 	if err != nil {
 		panic(err.Error())
 	}
 
-	maxPlayers, err := getArgMaxPlayers()
+	err = loadStats()
 	if err != nil {
 		panic(err.Error())
 	}
 
-	addPlayerChan := make(chan *player)
-	removePlayerChan := make(chan *player)
+	err = loadRatings()
+	if err != nil {
+		panic(err.Error())
+	}
 
-	// goroutine to add and remove players from joinedPlayers
-	go func(addPlayers <-chan *player, removePlayers <-chan *player) {
-		for {
-			select {
-			case p := <-addPlayers:
-				joinedPlayers = append(joinedPlayers, p)
-
-			case p := <-removePlayers:
-				// find and remove player
-				for i, jp := range joinedPlayers {
-					if jp == p {
-						joinedPlayers = append(joinedPlayers[:i], joinedPlayers[i+1:]...)
-						break
-					}
-				}
-			}
-		}
-	}(addPlayerChan, removePlayerChan)
+	err = loadBans()
+	if err != nil {
+		panic(err.Error())
+	}
 
-	for {
-		log.Println("waiting for players to join...")
+	if cfg.WordFilter != "" {
+		err = loadWordFilter(cfg.WordFilter)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
 
-		var wg sync.WaitGroup
+	// the default room is always there, players join it unless they choose another one
+	globVarMutex.Lock()
+	newRoom(false, "")
+	globVarMutex.Unlock()
+
+	go handleSignals()
+
+	// let the clients on the local network find the server
+	discoveryCtx, stopDiscovery := context.WithCancel(context.Background())
+	if !cfg.NoDiscovery {
+		go func() {
+			err := netutils.ServeDiscovery(discoveryCtx, func() netutils.ServerInfo {
+				globVarMutex.Lock()
+				defer globVarMutex.Unlock()
+
+				return discoveryInfo(cfg.ServerName, uint16(cfg.Port))
+			})
+			if err != nil {
+				slog.Warn("unable to answer discovery queries", "err", err)
+			}
+		}()
+	}
 
-		// let players connect
-		for len(joinedPlayers) < maxPlayers {
-			if connectedPlayers < maxPlayers {
-				conn, err := lis.Accept()
-				if err != nil {
-					log.Println(err.Error())
-				}
+	servers := make([]*http.Server, 0)
+	if cfg.AdminAddress != "" {
+		servers = append(servers, newAdminServer(cfg.AdminAddress, cfg.AdminToken))
+	}
+	if cfg.MetricsAddress != "" {
+		servers = append(servers, newMetricsServer(cfg.MetricsAddress))
+	}
 
-				connectedPlayers++
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			slog.Info("serving HTTP", "addr", srv.Addr)
+			err := srv.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("unable to serve HTTP", "addr", srv.Addr, "err", err)
+			}
+		}(srv)
+	}
 
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					handler(conn, maxPlayers, addPlayerChan, removePlayerChan)
-				}()
-			} else {
-				time.Sleep(200 * time.Millisecond)
+	// goroutine to enforce the turn time
+	go func() {
+		for {
+			globVarMutex.Lock()
+			for _, r := range rooms {
+				r.checkTurnTime()
 			}
+			globVarMutex.Unlock()
+
+			time.Sleep(500 * time.Millisecond)
 		}
+	}()
 
-		log.Println("all the players joined the game")
+	slog.Info("waiting for players to join", "addr", lis.Addr().String())
 
-		// give cards
-		cards := giveCards(len(joinedPlayers))
-		for i := range joinedPlayers {
-			joinedPlayers[i].cards = cards[i]
-			log.Println("cards have been assigned to " + joinedPlayers[i].name)
+	// let players connect, also while a game is running so that anyone can look at the statistics or join another room
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			} else if err != nil {
+				slog.Error("unable to accept a connection", "err", err)
+				continue
+			}
+
+			acceptConn(conn)
 		}
+	}()
 
-		wg.Wait()
-	}
+	<-shutdownRequests
+	shutdown(lis, servers, stopDiscovery, time.Duration(cfg.ShutdownTimeout)*time.Second)
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// requests which are counted by type, the others are counted as "invalid"
+var requestTypes []string = []string{"register", "login", "join", "spectate", "chat", "mute", "unmute", "kick", "set", "ready", "start", "get", "place", "dubito", "leave", "ping"}
+
+// upper bounds of the buckets of the request latency histogram, in seconds
+var latencyBuckets []float64 = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+type histogram struct {
+	counts []uint64 // counts[i] is the number of observations in the bucket latencyBuckets[i], the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets)+1)
+	}
+
+	i := sort.SearchFloat64s(latencyBuckets, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// metrics of the server, read them only while holding metricsMutex
+var requestCounts map[string]uint64 = make(map[string]uint64)
+var requestLatencies map[string]*histogram = make(map[string]*histogram)
+var protocolErrors uint64
+var connTimeouts uint64
+var rateLimited uint64
+var panics uint64
+var gamesStarted uint64
+var gamesFinished uint64
+var gameDurationSum float64 // seconds
+var metricsMutex sync.Mutex
+
+// return the type of the request as used in the metrics
+func requestType(name string) string {
+	for _, t := range requestTypes {
+		if t == name {
+			return t
+		}
+	}
+
+	return "invalid"
+}
+
+// record a request of type name which took d to be handled
+func recordRequest(name string, d time.Duration) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	t := requestType(name)
+	requestCounts[t]++
+
+	h, ok := requestLatencies[t]
+	if !ok {
+		h = new(histogram)
+		requestLatencies[t] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// record a request which the server could not read or understand
+func recordProtocolError() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	protocolErrors++
+}
+
+// record a connection closed because it sent nothing for too long
+func recordConnTimeout() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	connTimeouts++
+}
+
+// record a request refused because its connection sent too many requests
+func recordRateLimited() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	rateLimited++
+}
+
+// record a request which caused a panic
+func recordPanic() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	panics++
+}
+
+func recordGameStarted() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	gamesStarted++
+}
+
+func recordGameFinished(duration time.Duration) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	gamesFinished++
+	gameDurationSum += duration.Seconds()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// write the metrics in the Prometheus text format
+func writeMetrics(w io.Writer) {
+	// the state of the rooms
+	globVarMutex.Lock()
+	connected := connectedPlayers
+	roomCount := len(rooms)
+	joined, watching, running := 0, 0, 0
+	for _, r := range rooms {
+		joined += len(r.players)
+		watching += len(r.spectators)
+		if r.gameStarted && !r.gameOver {
+			running++
+		}
+	}
+	globVarMutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP dubito_connected_players Open connections, including the ones which did not join a room.")
+	fmt.Fprintln(w, "# TYPE dubito_connected_players gauge")
+	fmt.Fprintf(w, "dubito_connected_players %d\n", connected)
+	fmt.Fprintln(w, "# HELP dubito_joined_players Players who joined a room.")
+	fmt.Fprintln(w, "# TYPE dubito_joined_players gauge")
+	fmt.Fprintf(w, "dubito_joined_players %d\n", joined)
+	fmt.Fprintln(w, "# HELP dubito_spectators Players who are spectating a room.")
+	fmt.Fprintln(w, "# TYPE dubito_spectators gauge")
+	fmt.Fprintf(w, "dubito_spectators %d\n", watching)
+	fmt.Fprintln(w, "# HELP dubito_rooms Rooms which exist.")
+	fmt.Fprintln(w, "# TYPE dubito_rooms gauge")
+	fmt.Fprintf(w, "dubito_rooms %d\n", roomCount)
+	fmt.Fprintln(w, "# HELP dubito_games_running Games which started and are not over.")
+	fmt.Fprintln(w, "# TYPE dubito_games_running gauge")
+	fmt.Fprintf(w, "dubito_games_running %d\n", running)
+
+	// the state of the process, to spot leaks
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	fmt.Fprintln(w, "# HELP go_goroutines Number of goroutines that currently exist.")
+	fmt.Fprintln(w, "# TYPE go_goroutines gauge")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+	fmt.Fprintln(w, "# HELP go_memstats_heap_alloc_bytes Number of heap bytes allocated and still in use.")
+	fmt.Fprintln(w, "# TYPE go_memstats_heap_alloc_bytes gauge")
+	fmt.Fprintf(w, "go_memstats_heap_alloc_bytes %d\n", mem.HeapAlloc)
+
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP dubito_games_started_total Games which started.")
+	fmt.Fprintln(w, "# TYPE dubito_games_started_total counter")
+	fmt.Fprintf(w, "dubito_games_started_total %d\n", gamesStarted)
+	fmt.Fprintln(w, "# HELP dubito_games_finished_total Games which ended with a winner.")
+	fmt.Fprintln(w, "# TYPE dubito_games_finished_total counter")
+	fmt.Fprintf(w, "dubito_games_finished_total %d\n", gamesFinished)
+	fmt.Fprintln(w, "# HELP dubito_game_duration_seconds Duration of the games which ended with a winner.")
+	fmt.Fprintln(w, "# TYPE dubito_game_duration_seconds summary")
+	fmt.Fprintf(w, "dubito_game_duration_seconds_sum %s\n", formatFloat(gameDurationSum))
+	fmt.Fprintf(w, "dubito_game_duration_seconds_count %d\n", gamesFinished)
+	fmt.Fprintln(w, "# HELP dubito_protocol_errors_total Requests which could not be read or understood.")
+	fmt.Fprintln(w, "# TYPE dubito_protocol_errors_total counter")
+	fmt.Fprintf(w, "dubito_protocol_errors_total %d\n", protocolErrors)
+	fmt.Fprintln(w, "# HELP dubito_connection_timeouts_total Connections closed because they sent nothing for too long.")
+	fmt.Fprintln(w, "# TYPE dubito_connection_timeouts_total counter")
+	fmt.Fprintf(w, "dubito_connection_timeouts_total %d\n", connTimeouts)
+	fmt.Fprintln(w, "# HELP dubito_rate_limited_total Requests refused because their connection sent too many requests.")
+	fmt.Fprintln(w, "# TYPE dubito_rate_limited_total counter")
+	fmt.Fprintf(w, "dubito_rate_limited_total %d\n", rateLimited)
+	fmt.Fprintln(w, "# HELP dubito_panics_total Requests which caused a panic, their connections have been closed.")
+	fmt.Fprintln(w, "# TYPE dubito_panics_total counter")
+	fmt.Fprintf(w, "dubito_panics_total %d\n", panics)
+
+	types := make([]string, 0, len(requestCounts))
+	for t := range requestCounts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	fmt.Fprintln(w, "# HELP dubito_requests_total Requests handled, by type.")
+	fmt.Fprintln(w, "# TYPE dubito_requests_total counter")
+	for _, t := range types {
+		fmt.Fprintf(w, "dubito_requests_total{type=%q} %d\n", t, requestCounts[t])
+	}
+
+	fmt.Fprintln(w, "# HELP dubito_request_duration_seconds Time taken to handle a request and send its response, by type.")
+	fmt.Fprintln(w, "# TYPE dubito_request_duration_seconds histogram")
+	for _, t := range types {
+		h := requestLatencies[t]
+
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "dubito_request_duration_seconds_bucket{type=%q,le=%q} %d\n", t, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(w, "dubito_request_duration_seconds_bucket{type=%q,le=\"+Inf\"} %d\n", t, h.count)
+		fmt.Fprintf(w, "dubito_request_duration_seconds_sum{type=%q} %s\n", t, formatFloat(h.sum))
+		fmt.Fprintf(w, "dubito_request_duration_seconds_count{type=%q} %d\n", t, h.count)
+	}
+}
+
+// return the HTTP server serving the metrics at /metrics
+func newMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w)
+	})
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
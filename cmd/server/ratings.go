@@ -0,0 +1,173 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ratingsFile = "ratings.json"
+
+// rating given to players who never played a ranked game
+const initialRating = 1500.0
+
+// maximum rating change in a game, it is split among the opponents
+const ratingK = 32.0
+
+type ratingChange struct {
+	Time   time.Time `json:"time"`
+	Rating float64   `json:"rating"`
+}
+
+// Elo rating of a player, only players with an account have a rating
+type playerRating struct {
+	Name    string         `json:"name"`
+	Rating  float64        `json:"rating"`
+	History []ratingChange `json:"history"`
+}
+
+// ratings of all the players, the key is the lowercase name
+var ratings map[string]*playerRating = make(map[string]*playerRating)
+
+// mutex for ratings
+var ratingsMutex sync.Mutex
+
+func loadRatings() error {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+
+	list := make([]*playerRating, 0)
+	err := loadJSON(dataPath(ratingsFile), &list)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range list {
+		ratings[strings.ToLower(r.Name)] = r
+	}
+
+	return nil
+}
+
+// ratingsMutex must be held by the caller
+func saveRatings() error {
+	list := make([]*playerRating, 0, len(ratings))
+	for _, r := range ratings {
+		list = append(list, r)
+	}
+
+	return saveJSON(dataPath(ratingsFile), list)
+}
+
+// return the rating of the player, creating it if needed
+// ratingsMutex must be held by the caller
+func getPlayerRating(name string) *playerRating {
+	key := strings.ToLower(name)
+
+	r, ok := ratings[key]
+	if !ok {
+		r = &playerRating{Name: name, Rating: initialRating}
+		ratings[key] = r
+	}
+
+	return r
+}
+
+// return the last rating change, 0 if there is none
+func (r *playerRating) lastChange() float64 {
+	n := len(r.History)
+
+	if n == 0 {
+		return 0
+	} else if n == 1 {
+		return r.History[0].Rating - initialRating
+	}
+
+	return r.History[n-1].Rating - r.History[n-2].Rating
+}
+
+// expected score of a player with rating a against a player with rating b
+func expectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// update the ratings after a ranked game
+// placements[i] is the placement of players[i], equal placements mean a tie
+// the game is treated as a set of one-to-one matches between every pair of players
+func updateRatings(players []*player, placements []int) {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+
+	// only players with an account are rated
+	rated := make([]*playerRating, 0, len(players))
+	ratedPlacements := make([]int, 0, len(players))
+	for i, p := range players {
+		if p.account {
+			rated = append(rated, getPlayerRating(p.name))
+			ratedPlacements = append(ratedPlacements, placements[i])
+		}
+	}
+
+	if len(rated) < 2 {
+		return
+	}
+
+	// compute all the changes before applying them, so that the order does not matter
+	changes := make([]float64, len(rated))
+	for i := range rated {
+		for j := range rated {
+			if i == j {
+				continue
+			}
+
+			score := 0.5
+			if ratedPlacements[i] < ratedPlacements[j] {
+				score = 1
+			} else if ratedPlacements[i] > ratedPlacements[j] {
+				score = 0
+			}
+
+			changes[i] += score - expectedScore(rated[i].Rating, rated[j].Rating)
+		}
+	}
+
+	now := time.Now()
+	for i, r := range rated {
+		r.Rating += ratingK * changes[i] / float64(len(rated)-1)
+		r.History = append(r.History, ratingChange{Time: now, Rating: r.Rating})
+	}
+
+	err := saveRatings()
+	if err != nil {
+		slog.Error("unable to save the ratings", "err", err)
+	}
+}
+
+// the ratings response message has a line for each player, in the same order as players, structured as follows:
+// [player name] [rating] [last rating change]
+//
+// the rating and its change are rounded to integers and both are "-" if the player has no account
+func ratingsResponse(players []*player) string {
+	ratingsMutex.Lock()
+	defer ratingsMutex.Unlock()
+
+	lines := make([]string, len(players))
+	for i, p := range players {
+		if !p.account {
+			lines[i] = p.name + " - -"
+			continue
+		}
+
+		rating, change := initialRating, 0.0
+		if r, ok := ratings[strings.ToLower(p.name)]; ok {
+			rating, change = r.Rating, r.lastChange()
+		}
+
+		lines[i] = p.name + " " + strconv.Itoa(int(math.Round(rating))) + " " + strconv.Itoa(int(math.Round(change)))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+)
+
+// longest request accepted, in bytes, the longest ones are chat messages
+const maxRequestLength = 1024
+
+// each connection can send up to requestBurst requests at once and requestRate requests per second afterwards,
+// the clients poll the state of the game about 10 times per second
+const requestRate = 25
+const requestBurst = 50
+
+// arguments taken by a request, max is -1 if there is no limit
+type requestArgs struct {
+	min   int
+	max   int
+	usage string
+}
+
+var requestsArgs map[string]requestArgs = map[string]requestArgs{
+	"register": {2, -1, "register <name> <password>"},
+	"login":    {2, -1, "login <name> <password>"},
+	"join":     {0, 3, "join [name] [room] [password or invite code]"},
+	"spectate": {0, 3, "spectate [name] [room] [password or invite code]"},
+	"chat":     {1, -1, "chat <message>"},
+	"mute":     {1, 1, "mute <name>"},
+	"unmute":   {1, 1, "unmute <name>"},
+	"kick":     {1, 1, "kick <name>"},
+	"set":      {2, 2, "set <setting> <value>"},
+	"ready":    {1, 1, "ready <y/n>"},
+	"start":    {0, 0, "start"},
+	"get":      {1, 2, "get <what> [argument]"},
+	"place":    {1, -1, "place <card>[,<card>...] [as <rank>]"},
+	"dubito":   {0, 0, "dubito"},
+	"ping":     {0, 0, "ping"},
+	"leave":    {0, 0, "leave"},
+}
+
+// arguments taken by the "get" requests, after what is requested
+var getArgs map[string]requestArgs = map[string]requestArgs{
+	"chat":        {0, 1, "get chat [first message id]"},
+	"stats":       {1, 1, "get stats <name>"},
+	"leaderboard": {0, 0, "get leaderboard"},
+	"rooms":       {0, 0, "get rooms"},
+	"players":     {0, 0, "get players"},
+	"room":        {0, 0, "get room"},
+	"ratings":     {0, 0, "get ratings"},
+	"max-players": {0, 0, "get max-players"},
+	"cards":       {0, 0, "get cards"},
+	"update":      {0, 0, "get update"},
+	"standings":   {0, 0, "get standings"},
+	"table":       {0, 0, "get table"},
+}
+
+func checkArgs(args []string, ra requestArgs) error {
+	if len(args) < ra.min || (ra.max != -1 && len(args) > ra.max) {
+		return fmt.Errorf("usage: " + ra.usage)
+	}
+
+	return nil
+}
+
+// split a request into its fields and check that the request exists and has the right number of arguments
+// the first field is the name of the request, the returned error is meant to be sent to the client
+func parseRequest(msg string) ([]string, error) {
+	fields := strings.Fields(msg)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty request")
+	}
+
+	ra, ok := requestsArgs[fields[0]]
+	if !ok {
+		return fields, fmt.Errorf("unknown request")
+	}
+
+	err := checkArgs(fields[1:], ra)
+	if err != nil {
+		return fields, err
+	}
+
+	if fields[0] == "get" {
+		ga, ok := getArgs[fields[1]]
+		if !ok {
+			return fields, fmt.Errorf("unknown request")
+		}
+
+		err = checkArgs(fields[2:], ga)
+		if err != nil {
+			return fields, err
+		}
+
+		if fields[1] == "chat" && len(fields) == 3 {
+			if id, err := strconv.Atoi(fields[2]); err != nil || id < 0 {
+				return fields, fmt.Errorf("usage: " + ga.usage)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// parse the arguments of a "place" request, such as "five clubs,queen spades" or "5C,QS as five"
+// the rank which the player claims follows "as", it is 0 if the request does not tell it
+func parsePlace(args []string) ([]cardutils.Card, cardutils.Rank, error) {
+	var claim cardutils.Rank
+
+	if n := len(args); n >= 2 && args[n-2] == "as" {
+		r, err := cardutils.RankByName(args[n-1])
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid rank: %s", args[n-1])
+		}
+
+		claim = r
+		args = args[:n-2]
+	}
+
+	cards, err := parseCards(strings.Join(args, " "))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cards, claim, nil
+}
+
+// parse the cards of a "place" request, such as "five clubs,queen spades" or "5C,QS"
+func parseCards(arg string) ([]cardutils.Card, error) {
+	cardsStr := strings.Split(arg, ",")
+	if len(cardsStr) > 4 {
+		return nil, fmt.Errorf("too many cards")
+	}
+
+	cards := make([]cardutils.Card, len(cardsStr))
+	placed := make(map[cardutils.Card]bool)
+
+	for i, cs := range cardsStr {
+		card, err := cardutils.ParseCard(cs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid card: %s", strings.TrimSpace(cs))
+		}
+
+		if placed[card] {
+			return nil, fmt.Errorf("you placed %s more than once", cardutils.CardToString(card))
+		}
+
+		placed[card] = true
+		cards[i] = card
+	}
+
+	return cards, nil
+}
+
+// token bucket limiting the requests of a connection
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{tokens: requestBurst, last: time.Now()}
+}
+
+// return true if a request can be handled now, taking a token
+func (rl *rateLimiter) allow() bool {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * requestRate
+	if rl.tokens > requestBurst {
+		rl.tokens = requestBurst
+	}
+	rl.last = now
+
+	if rl.tokens < 1 {
+		return false
+	}
+
+	rl.tokens--
+	return true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+)
+
+func FuzzParseRequest(f *testing.F) {
+	for _, req := range []string{"", "join alice 2 secret", "get", "get chat 3", "get chat -1", "get stats", "place ace spades,two hearts", "set variant placement", "ready y y"} {
+		f.Add(req)
+	}
+
+	f.Fuzz(func(t *testing.T, msg string) {
+		fields, err := parseRequest(msg)
+		if err != nil {
+			return
+		}
+
+		// the handler relies on these checks to index the fields
+		ra, ok := requestsArgs[fields[0]]
+		if !ok {
+			t.Fatalf("%q: unknown request accepted", msg)
+		}
+
+		args := len(fields) - 1
+		if args < ra.min || (ra.max != -1 && args > ra.max) {
+			t.Fatalf("%q: %d arguments accepted, want %d to %d", msg, args, ra.min, ra.max)
+		}
+
+		if fields[0] == "get" {
+			if _, ok := getArgs[fields[1]]; !ok {
+				t.Fatalf("%q: unknown get request accepted", msg)
+			}
+		}
+	})
+}
+
+func FuzzParseCards(f *testing.F) {
+	for _, arg := range []string{"ace spades", "ace spades,two hearts", "ace spades,ace spades", "ace", ",", "", "a,b,c,d,e"} {
+		f.Add(arg)
+	}
+
+	f.Fuzz(func(t *testing.T, arg string) {
+		cards, err := parseCards(arg)
+		if err != nil {
+			return
+		}
+
+		if len(cards) < 1 || len(cards) > 4 {
+			t.Fatalf("%q: %d cards accepted", arg, len(cards))
+		}
+
+		seen := make(map[cardutils.Card]bool)
+		for _, c := range cards {
+			if seen[c] {
+				t.Fatalf("%q: %s accepted twice", arg, cardutils.CardToString(c))
+			}
+			seen[c] = true
+
+			if _, err := cardutils.CardByName(cardutils.CardToString(c)); err != nil {
+				t.Fatalf("%q: invalid card accepted: %v", arg, err)
+			}
+		}
+
+		if len(cards) != len(strings.Split(arg, ",")) {
+			t.Fatalf("%q: some cards were dropped", arg)
+		}
+	})
+}
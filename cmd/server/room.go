@@ -0,0 +1,711 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+	"github.com/EdoardoLaGreca/dubito/internal/netutils"
+)
+
+// absolute limits for the number of players
+const minPlayersLimit = 2
+const maxPlayersLimit = 13
+
+// longest turn time which can be set, in seconds
+const maxTurnTime = 600
+
+// id of the public room which always exists
+const defaultRoomID = "1"
+
+// invite codes are made of letters only, so that they cannot be mistaken for room ids
+const inviteCodeLength = 6
+const inviteCodeLetters = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+type room struct {
+	id         string
+	private    bool   // private rooms are not in the list of rooms
+	inviteCode string // empty if the room is public
+	password   string // empty if there is no password
+
+	players    []*player // the first one is the host
+	spectators []*player // players who watch the game without joining, they have no cards
+
+	// settings of the game, which the host can change before the game starts
+	minPlayers int
+	maxPlayers int
+	turnTime   int  // seconds a player has to place cards, 0 if there is no limit
+	ranked     bool // true if the ratings change at the end of the game
+	// true if the game goes on after the first player finishes, until the full placement order is known ("placement" variant)
+	// false if it ends as soon as the first player finishes ("classic" variant)
+	fullPlacement bool
+
+	gameID          int // 0 until the first game starts
+	currentTurn     int
+	currentRank     cardutils.Rank // rank which the next player has to place
+	placedCards     []cardutils.Card
+	lastPlacedCards []cardutils.Card
+	lastPlacer      *player        // player who placed lastPlacedCards
+	lastClaimRank   cardutils.Rank // rank claimed by lastPlacer
+	gameStarted     bool           // true once the cards have been given
+	gameOver        bool
+	aborted         bool // true if the game was ended by an administrator, nobody won it
+	gameBeginning   time.Time
+	standings       []*player    // players who finished the game, from the first one
+	finalPlacements []int        // placements of the players in standings, set when the game is over
+	pendingFinisher *player      // player who placed their last cards, they finish unless their play is doubted right
+	leavers         []*player    // players who left the running game before finishing it, they are placed last
+	tableHistory    []tableEvent // plays and doubts of the current game, from the oldest one
+
+	// player whose turn is being timed and when the turn began
+	timedPlayer   *player
+	turnBeginning time.Time
+
+	chatMessages []chatMessage
+	nextChatID   int
+	mutedPlayers map[string]bool // lowercase names of the muted players
+}
+
+// default settings of new rooms
+var defaultMinPlayers int
+var defaultMaxPlayers int
+var defaultFullPlacement bool
+var defaultTurnTime int
+var defaultRanked bool
+
+// all the rooms, by id
+var rooms map[string]*room = make(map[string]*room)
+var lastRoomID int
+
+// games are numbered across all the rooms, so that the log of a game can be found
+var lastGameID int
+
+// create a room with the default settings
+// globVarMutex must be held by the caller
+func newRoom(private bool, password string) *room {
+	lastRoomID++
+
+	r := &room{
+		id:            strconv.Itoa(lastRoomID),
+		private:       private,
+		password:      password,
+		minPlayers:    defaultMinPlayers,
+		maxPlayers:    defaultMaxPlayers,
+		turnTime:      defaultTurnTime,
+		ranked:        defaultRanked,
+		fullPlacement: defaultFullPlacement,
+		currentRank:   cardutils.Ace,
+		mutedPlayers:  make(map[string]bool),
+	}
+
+	if private {
+		r.inviteCode = newInviteCode()
+	}
+
+	rooms[r.id] = r
+
+	r.logger().Info("room created", "private", private)
+
+	return r
+}
+
+// generate an invite code which is not used by any other room
+// globVarMutex must be held by the caller
+func newInviteCode() string {
+	for {
+		code := make([]byte, inviteCodeLength)
+		for i := range code {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(inviteCodeLetters))))
+			if err != nil {
+				panic(err.Error())
+			}
+			code[i] = inviteCodeLetters[n.Int64()]
+		}
+
+		if getRoomByCode(string(code)) == nil {
+			return string(code)
+		}
+	}
+}
+
+// return the room with the invite code, regardless of the letter case, or nil if there is none
+// globVarMutex must be held by the caller
+func getRoomByCode(code string) *room {
+	for _, r := range rooms {
+		if r.private && strings.EqualFold(r.inviteCode, code) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// find the room to join or spectate
+// roomArg is either a room id or an invite code, while secret is the password or the invite code of a private room
+// roomArg can also be "new" or "new-private" to create a room, in which case secret is the password of the new private room
+// globVarMutex must be held by the caller
+func findRoom(roomArg, secret string) (*room, error) {
+	switch roomArg {
+	case "":
+		return rooms[defaultRoomID], nil
+	case "new":
+		return newRoom(false, ""), nil
+	case "new-private":
+		return newRoom(true, secret), nil
+	}
+
+	if r := getRoomByCode(roomArg); r != nil {
+		return r, nil
+	}
+
+	r, ok := rooms[roomArg]
+	if !ok {
+		return nil, fmt.Errorf("unknown room")
+	}
+
+	if r.private {
+		if secret == "" {
+			return nil, fmt.Errorf("the room is private, a password or an invite code is needed")
+		}
+
+		if (r.password == "" || secret != r.password) && !strings.EqualFold(secret, r.inviteCode) {
+			return nil, fmt.Errorf("wrong password or invite code")
+		}
+	}
+
+	return r, nil
+}
+
+// globVarMutex must be held by the caller
+func (r *room) addPlayer(p *player) {
+	p.room = r
+	r.players = append(r.players, p)
+}
+
+// remove a player, keeping the turn on the same player or moving it to the next one if it was the turn of the removed player
+// globVarMutex must be held by the caller
+func (r *room) removePlayer(p *player) {
+	i := r.getPlayerIndex(p)
+	if i == -1 {
+		return
+	}
+
+	r.players = append(r.players[:i], r.players[i+1:]...)
+
+	if i < r.currentTurn {
+		r.currentTurn--
+	}
+	if r.currentTurn >= len(r.players) {
+		r.currentTurn = 0
+	}
+	if r.gameStarted && len(r.players) > 0 && r.players[r.currentTurn].cards.Len() == 0 {
+		r.currentTurn = r.getNextTurn(r.currentTurn)
+	}
+
+	if r.gameStarted && !r.gameOver {
+		r.playerLeftGame(p)
+	}
+
+	r.deleteIfEmpty()
+}
+
+// forget the part p had in the running game, which ends if there are not enough players left
+// globVarMutex must be held by the caller
+func (r *room) playerLeftGame(p *player) {
+	if r.pendingFinisher == p {
+		r.pendingFinisher = nil
+	}
+
+	// the cards placed by p stay on the table, but nobody can doubt of them anymore
+	if r.lastPlacer == p {
+		r.lastPlacer = nil
+		r.lastPlacedCards = nil
+	}
+
+	if r.getPlacement(p) == 0 {
+		r.leavers = append(r.leavers, p)
+	}
+
+	if len(r.players) == 0 {
+		return
+	}
+
+	// count the players who still play
+	playing := 0
+	for _, jp := range r.players {
+		if jp.cards.Len() > 0 {
+			playing++
+		}
+	}
+
+	if len(r.players) < 2 || (r.pendingFinisher == nil && playing < 2) {
+		r.logger().Info("not enough players left, the game ends")
+		r.endGame()
+	}
+}
+
+// globVarMutex must be held by the caller
+func (r *room) addSpectator(s *player) {
+	s.room = r
+	r.spectators = append(r.spectators, s)
+}
+
+// globVarMutex must be held by the caller
+func (r *room) removeSpectator(s *player) {
+	for i, sp := range r.spectators {
+		if sp == s {
+			r.spectators = append(r.spectators[:i], r.spectators[i+1:]...)
+			break
+		}
+	}
+
+	r.deleteIfEmpty()
+}
+
+// once everybody left, the room is deleted, or reset if it is the default room
+// globVarMutex must be held by the caller
+func (r *room) deleteIfEmpty() {
+	if len(r.players) > 0 || len(r.spectators) > 0 {
+		return
+	}
+
+	if r.id == defaultRoomID {
+		r.resetGame()
+	} else {
+		delete(rooms, r.id)
+		r.logger().Info("room deleted")
+	}
+}
+
+// return the index of p in the joined players, -1 if p is not there
+func (r *room) getPlayerIndex(p *player) int {
+	for i, jp := range r.players {
+		if jp == p {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// return true if p is the host of the room, who can change the settings and moderate the chat
+func (r *room) isHost(p *player) bool {
+	return len(r.players) > 0 && r.players[0] == p
+}
+
+// check if the current turn is the player's turn
+func (r *room) checkPlayerTurn(p *player) bool {
+	return r.players[r.currentTurn] == p
+}
+
+// return the rank claimed by a play, claim is 0 if the player did not tell it, which claims the current rank
+// the player who starts the pile can claim any rank, the others have to claim the rank after the last claim
+func (r *room) checkClaim(claim cardutils.Rank) (cardutils.Rank, error) {
+	if claim == 0 {
+		return r.currentRank, nil
+	}
+
+	if r.lastPlacer != nil && claim != r.currentRank {
+		return 0, fmt.Errorf("you have to claim %s", cardutils.RankToString(r.currentRank))
+	}
+
+	return claim, nil
+}
+
+// return the index of the next player who still has cards, starting from the one after i
+// players without cards either finished or are waiting for their last play to be accepted
+func (r *room) getNextTurn(i int) int {
+	for j := 1; j <= len(r.players); j++ {
+		next := (i + j) % len(r.players)
+		if r.players[next].cards.Len() > 0 {
+			return next
+		}
+	}
+
+	return i
+}
+
+// return the placement of the player (1 for the winner), 0 if the player did not finish yet
+func (r *room) getPlacement(p *player) int {
+	for i, sp := range r.standings {
+		if sp == p {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// the last play of pendingFinisher was accepted, they finish the game
+// globVarMutex must be held by the caller
+func (r *room) confirmFinisher() {
+	p := r.pendingFinisher
+	r.pendingFinisher = nil
+	r.standings = append(r.standings, p)
+	p.logger().Info("player finished", "position", len(r.standings))
+
+	// count the players who still play
+	playing := 0
+	for _, jp := range r.players {
+		if jp.cards.Len() > 0 {
+			playing++
+		}
+	}
+
+	if !r.fullPlacement || playing <= 1 {
+		r.endGame()
+	}
+}
+
+// end the game and record its outcome
+// globVarMutex must be held by the caller
+func (r *room) endGame() {
+	r.gameOver = true
+
+	// complete the standings with the players who did not finish, then the players who left, the last one to leave first
+	r.standings, r.finalPlacements = getPlacements(r.players, r.standings)
+	for i := len(r.leavers) - 1; i >= 0; i-- {
+		r.standings = append(r.standings, r.leavers[i])
+		r.finalPlacements = append(r.finalPlacements, len(r.finalPlacements)+1)
+	}
+
+	winner := r.standings[0]
+	winner.logger().Info("player won the game")
+
+	recordGameEnd(winner)
+	recordGameFinished(time.Since(r.gameBeginning))
+
+	if r.ranked {
+		updateRatings(r.standings, r.finalPlacements)
+	}
+}
+
+// end the game without a winner and without recording it, the players are placed by the number of cards they hold
+// globVarMutex must be held by the caller
+func (r *room) abortGame() {
+	r.gameOver = true
+	r.aborted = true
+	r.pendingFinisher = nil
+	r.standings, r.finalPlacements = getPlacements(r.players, r.standings)
+
+	r.logger().Info("the game has been aborted")
+}
+
+// compute the placements of the players at the end of a game
+// the players who finished come first, in the order in which they finished, while the others are ranked by the number of cards they still hold
+func getPlacements(players []*player, finished []*player) ([]*player, []int) {
+	sorted := make([]*player, 0, len(players))
+	sorted = append(sorted, finished...)
+
+	isFinished := make(map[*player]bool)
+	for _, p := range finished {
+		isFinished[p] = true
+	}
+
+	rest := make([]*player, 0, len(players))
+	for _, p := range players {
+		if !isFinished[p] {
+			rest = append(rest, p)
+		}
+	}
+
+	sort.SliceStable(rest, func(i, j int) bool {
+		return rest[i].cards.Len() < rest[j].cards.Len()
+	})
+
+	placements := make([]int, len(finished), len(finished)+len(rest))
+	for i := range finished {
+		placements[i] = i + 1
+	}
+
+	for i, p := range rest {
+		if i > 0 && p.cards.Len() == rest[i-1].cards.Len() {
+			placements = append(placements, placements[len(placements)-1])
+		} else {
+			placements = append(placements, len(placements)+1)
+		}
+	}
+
+	return append(sorted, rest...), placements
+}
+
+// reset the state of the game
+// globVarMutex must be held by the caller
+func (r *room) resetGame() {
+	r.currentTurn = 0
+	r.currentRank = cardutils.Ace
+	r.placedCards = nil
+	r.lastPlacedCards = nil
+	r.lastPlacer = nil
+	r.gameStarted = false
+	r.gameOver = false
+	r.aborted = false
+	r.standings = nil
+	r.finalPlacements = nil
+	r.pendingFinisher = nil
+	r.leavers = nil
+	r.tableHistory = nil
+
+	for _, p := range r.players {
+		p.ready = false
+		p.cards = cardutils.Hand{}
+	}
+}
+
+func (r *room) getVariantName() string {
+	if r.fullPlacement {
+		return "placement"
+	}
+
+	return "classic"
+}
+
+// change a setting of the game, key and value come from a "set" request
+// globVarMutex must be held by the caller
+func (r *room) setSetting(key, value string) error {
+	switch key {
+	case "min-players", "max-players":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number: %s", value)
+		}
+
+		newMin, newMax := r.minPlayers, r.maxPlayers
+		if key == "min-players" {
+			newMin = n
+		} else {
+			newMax = n
+		}
+
+		if newMin < minPlayersLimit || newMax > maxPlayersLimit {
+			return fmt.Errorf("the number of players must be between %d and %d", minPlayersLimit, maxPlayersLimit)
+		} else if newMin > newMax {
+			return fmt.Errorf("the minimum number of players cannot be greater than the maximum")
+		} else if newMax < len(r.players) {
+			return fmt.Errorf("%d players already joined", len(r.players))
+		}
+
+		r.minPlayers, r.maxPlayers = newMin, newMax
+	case "variant":
+		switch value {
+		case "classic":
+			r.fullPlacement = false
+		case "placement":
+			r.fullPlacement = true
+		default:
+			return fmt.Errorf("unknown variant: %s", value)
+		}
+	case "turn-time":
+		t, err := strconv.Atoi(value)
+		if err != nil || t < 0 || t > maxTurnTime {
+			return fmt.Errorf("the turn time must be between 0 (no limit) and %d seconds", maxTurnTime)
+		}
+
+		r.turnTime = t
+	case "ranked":
+		switch value {
+		case "y":
+			r.ranked = true
+		case "n":
+			r.ranked = false
+		default:
+			return fmt.Errorf("usage: set ranked <y/n>")
+		}
+	default:
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+
+	return nil
+}
+
+// check whether the host can start the game, a new game can start once the last one is over
+// globVarMutex must be held by the caller
+func (r *room) checkCanStart() error {
+	if r.gameStarted && !r.gameOver {
+		return fmt.Errorf("the game already started")
+	}
+
+	if len(r.players) < r.minPlayers {
+		return fmt.Errorf("at least %d players are needed", r.minPlayers)
+	}
+
+	for _, p := range r.players {
+		// starting the game means that the host is ready
+		if !p.ready && !r.isHost(p) {
+			return fmt.Errorf("%s is not ready", p.name)
+		}
+	}
+
+	return nil
+}
+
+// give the cards and start the game
+// globVarMutex must be held by the caller
+func (r *room) startGame() {
+	r.resetGame()
+
+	lastGameID++
+	r.gameID = lastGameID
+
+	hands := giveCards(len(r.players))
+	for i := range r.players {
+		r.players[i].cards = hands[i]
+		if debugLogging() {
+			r.players[i].logger().Debug("cards have been assigned", "cards", strings.Join(cardutils.CardsToString(hands[i].Sort(cardutils.ByRank)), ","))
+		}
+	}
+
+	r.gameStarted = true
+	r.gameBeginning = time.Now()
+	recordGameStart(r.players)
+	recordGameStarted()
+
+	r.logger().Info("the game started", "players", len(r.players))
+}
+
+// skip the turn of the current player if they took too long
+// globVarMutex must be held by the caller
+func (r *room) checkTurnTime() {
+	if !r.gameStarted || r.gameOver || len(r.players) == 0 {
+		r.timedPlayer = nil
+		return
+	}
+
+	current := r.players[r.currentTurn]
+	if current != r.timedPlayer {
+		// the turn changed
+		r.timedPlayer = current
+		r.turnBeginning = time.Now()
+		return
+	}
+
+	if r.turnTime > 0 && time.Since(r.turnBeginning) > time.Duration(r.turnTime)*time.Second {
+		r.addChatMessage(serverChatName, current.name+" ran out of time")
+		current.logger().Info("player ran out of time")
+
+		r.currentTurn = r.getNextTurn(r.currentTurn)
+		r.timedPlayer = r.players[r.currentTurn]
+		r.turnBeginning = time.Now()
+	}
+}
+
+// the room response message is structured as follows:
+// [room id] [invite code, "-" if the room is public]\n
+// [minimum number of players] [maximum number of players]\n
+// [variant, either "classic" or "placement"]\n
+// [seconds a player has to place cards, 0 if there is no limit]\n
+// [y/n if the game is ranked]\n
+// [y/n if the game started]\n
+// [a line for each joined player, starting from the host, structured as "<name> <y/n if the player is ready>"]
+func (r *room) roomResponse() string {
+	lines := make([]string, 0, 6+len(r.players))
+
+	if r.private {
+		lines = append(lines, r.id+" "+r.inviteCode)
+	} else {
+		lines = append(lines, r.id+" -")
+	}
+
+	lines = append(lines, strconv.Itoa(r.minPlayers)+" "+strconv.Itoa(r.maxPlayers))
+	lines = append(lines, r.getVariantName())
+	lines = append(lines, strconv.Itoa(r.turnTime))
+
+	if r.ranked {
+		lines = append(lines, "y")
+	} else {
+		lines = append(lines, "n")
+	}
+
+	if r.gameStarted {
+		lines = append(lines, "y")
+	} else {
+		lines = append(lines, "n")
+	}
+
+	for _, p := range r.players {
+		if p.ready || r.isHost(p) {
+			lines = append(lines, p.name+" y")
+		} else {
+			lines = append(lines, p.name+" n")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// the standings response message has a line for each player who finished, from the first one, structured as follows:
+// [placement] [player name]
+//
+// once the game is over, all the players are included and players who did not finish may share the same placement
+func (r *room) standingsResponse() string {
+	lines := make([]string, len(r.standings))
+
+	if r.gameOver {
+		for i, p := range r.standings {
+			lines[i] = strconv.Itoa(r.finalPlacements[i]) + " " + p.name
+		}
+	} else {
+		for i, p := range r.standings {
+			lines[i] = strconv.Itoa(i+1) + " " + p.name
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// the rooms response message has a line for each public room, sorted by id, structured as follows:
+// [room id] [host name, "-" if the room is empty] [number of joined players] [maximum number of players] [y/n if the game started] [y/n if the game is ranked]
+//
+// globVarMutex must be held by the caller
+func roomsResponse() string {
+	ids := make([]int, 0, len(rooms))
+	for _, r := range rooms {
+		if !r.private {
+			id, _ := strconv.Atoi(r.id)
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	lines := make([]string, len(ids))
+	for i, id := range ids {
+		r := rooms[strconv.Itoa(id)]
+
+		host := "-"
+		if len(r.players) > 0 {
+			host = r.players[0].name
+		}
+
+		started := "n"
+		if r.gameStarted {
+			started = "y"
+		}
+
+		rankedGame := "n"
+		if r.ranked {
+			rankedGame = "y"
+		}
+
+		lines[i] = r.id + " " + host + " " + strconv.Itoa(len(r.players)) + " " + strconv.Itoa(r.maxPlayers) + " " + started + " " + rankedGame
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// return the state of the server sent to the clients looking for servers on the local network
+// globVarMutex must be held by the caller
+func discoveryInfo(name string, port uint16) netutils.ServerInfo {
+	freeSeats := 0
+	for _, r := range rooms {
+		if !r.gameStarted {
+			freeSeats += r.maxPlayers - len(r.players)
+		}
+	}
+
+	return netutils.ServerInfo{Name: name, Port: port, Rooms: len(rooms), FreeSeats: freeSeats}
+}
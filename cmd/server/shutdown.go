@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// time left to the players to see how their game ended before the connections are closed
+const shutdownGrace = 3 * time.Second
+
+// longest time to wait for the handlers to clean up after their connections are closed
+const handlersTimeout = 5 * time.Second
+
+// receives a value when the server has to shut down
+var shutdownRequests chan struct{} = make(chan struct{}, 1)
+
+// true once the server started shutting down, no game can start or be joined afterwards
+// read it only while holding globVarMutex
+var shuttingDown bool
+
+// running games are ended when the deadline passes
+var shutdownDeadline time.Time
+
+// running handlers, so that the server can wait for them to clean up
+var handlers sync.WaitGroup
+
+// ask the server to shut down, it can be called more than once
+func requestShutdown() {
+	select {
+	case shutdownRequests <- struct{}{}:
+	default:
+		// a shutdown was already requested
+	}
+}
+
+// shut down on SIGINT and SIGTERM, a second signal makes the server exit right away
+func handleSignals() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	s := <-signals
+	slog.Info("received a signal, shutting down", "signal", s.String())
+	requestShutdown()
+
+	s = <-signals
+	slog.Warn("received a second signal, exiting", "signal", s.String())
+	os.Exit(1)
+}
+
+// return the seconds left before the running games are ended, -1 if the server is not shutting down
+// globVarMutex must be held by the caller
+func shutdownCountdown() int {
+	if !shuttingDown {
+		return -1
+	}
+
+	left := int(time.Until(shutdownDeadline).Seconds())
+	if left < 0 {
+		return 0
+	}
+
+	return left
+}
+
+// globVarMutex must be held by the caller
+func announceShutdown(seconds int) {
+	for _, r := range rooms {
+		r.addChatMessage(serverChatName, "the server is shutting down in "+strconv.Itoa(seconds)+" seconds")
+	}
+}
+
+// return true if a game is running in any room
+// globVarMutex must be held by the caller
+func gamesRunning() bool {
+	for _, r := range rooms {
+		if r.gameStarted && !r.gameOver && len(r.players) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stop accepting connections, let the running games finish until the timeout and then close everything
+func shutdown(lis net.Listener, servers []*http.Server, stopDiscovery context.CancelFunc, timeout time.Duration) {
+	lis.Close()
+	stopDiscovery()
+
+	globVarMutex.Lock()
+	shuttingDown = true
+	shutdownDeadline = time.Now().Add(timeout)
+	announceShutdown(int(timeout.Seconds()))
+	globVarMutex.Unlock()
+
+	slog.Info("waiting for the running games to end", "timeout", timeout.String())
+
+	// remind the players once, when little time is left
+	reminded := timeout <= 10*time.Second
+	for {
+		globVarMutex.Lock()
+		if !gamesRunning() {
+			globVarMutex.Unlock()
+			break
+		}
+
+		if time.Now().After(shutdownDeadline) {
+			for _, r := range rooms {
+				if r.gameStarted && !r.gameOver {
+					r.abortGame()
+					r.addChatMessage(serverChatName, "the game has been ended because the server is shutting down")
+				}
+			}
+			globVarMutex.Unlock()
+			break
+		}
+
+		if !reminded && time.Until(shutdownDeadline) <= 10*time.Second {
+			reminded = true
+			announceShutdown(shutdownCountdown())
+		}
+		globVarMutex.Unlock()
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	time.Sleep(shutdownGrace)
+
+	slog.Info("closing the connections")
+
+	globVarMutex.Lock()
+	for conn := range openConns {
+		conn.Close()
+	}
+	globVarMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(handlersTimeout):
+		slog.Warn("some connections did not close in time")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handlersTimeout)
+	defer cancel()
+
+	for _, srv := range servers {
+		err := srv.Shutdown(ctx)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("unable to shut down an HTTP listener", "addr", srv.Addr, "err", err)
+		}
+	}
+
+	// the plays of the games ended at the deadline may not have been saved yet
+	flushStats()
+
+	slog.Info("the server has been shut down")
+}
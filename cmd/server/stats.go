@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const statsFile = "stats.json"
+
+const maxLeaderboardEntries = 20
+
+// the statistics recorded during a game are saved this long after the first change, so that the plays close to each
+// other are saved together
+const statsSaveDelay = 5 * time.Second
+
+// statistics of a player across games, only players with an account have statistics
+type playerStats struct {
+	Name            string `json:"name"`
+	GamesPlayed     int    `json:"gamesPlayed"`
+	GamesWon        int    `json:"gamesWon"`
+	BluffsAttempted int    `json:"bluffsAttempted"`
+	BluffsCaught    int    `json:"bluffsCaught"`
+	DoubtsCalled    int    `json:"doubtsCalled"`
+	DoubtsRight     int    `json:"doubtsRight"`
+	// sum of the number of cards held at the beginning of every turn, used for the average
+	CardsHeldSum int `json:"cardsHeldSum"`
+	Turns        int `json:"turns"`
+}
+
+// statistics of all the players, the key is the lowercase name
+var stats map[string]*playerStats = make(map[string]*playerStats)
+
+// mutex for stats
+var statsMutex sync.Mutex
+
+// pending save of the statistics, nil if there is none
+// read it only while holding statsMutex
+var statsSaveTimer *time.Timer
+
+func loadStats() error {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	list := make([]*playerStats, 0)
+	err := loadJSON(dataPath(statsFile), &list)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range list {
+		stats[strings.ToLower(s.Name)] = s
+	}
+
+	return nil
+}
+
+// statsMutex must be held by the caller
+func saveStats() error {
+	if statsSaveTimer != nil {
+		statsSaveTimer.Stop()
+		statsSaveTimer = nil
+	}
+
+	list := make([]*playerStats, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, s)
+	}
+
+	return saveJSON(dataPath(statsFile), list)
+}
+
+// save the statistics after statsSaveDelay, unless a save is already pending
+// statsMutex must be held by the caller
+func scheduleStatsSave() {
+	if statsSaveTimer != nil {
+		return
+	}
+
+	statsSaveTimer = time.AfterFunc(statsSaveDelay, func() {
+		statsMutex.Lock()
+		defer statsMutex.Unlock()
+
+		err := saveStats()
+		if err != nil {
+			slog.Error("unable to save the statistics", "err", err)
+		}
+	})
+}
+
+// save the statistics right away if a save is pending, such as before the server exits
+func flushStats() {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	if statsSaveTimer == nil {
+		return
+	}
+
+	err := saveStats()
+	if err != nil {
+		slog.Error("unable to save the statistics", "err", err)
+	}
+}
+
+// return the statistics of the player, creating them if needed
+// statsMutex must be held by the caller
+func getPlayerStats(name string) *playerStats {
+	key := strings.ToLower(name)
+
+	s, ok := stats[key]
+	if !ok {
+		s = &playerStats{Name: name}
+		stats[key] = s
+	}
+
+	return s
+}
+
+func (s *playerStats) avgCardsHeld() float64 {
+	if s.Turns == 0 {
+		return 0
+	}
+
+	return float64(s.CardsHeldSum) / float64(s.Turns)
+}
+
+func recordGameStart(players []*player) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	for _, p := range players {
+		if p.account {
+			getPlayerStats(p.name).GamesPlayed++
+		}
+	}
+
+	scheduleStatsSave()
+}
+
+func recordGameEnd(winner *player) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	if winner.account {
+		getPlayerStats(winner.name).GamesWon++
+	}
+
+	scheduleStatsSave()
+}
+
+// record a turn in which p held cardsHeld cards and placed cards, possibly bluffing
+func recordPlace(p *player, cardsHeld int, bluff bool) {
+	if !p.account {
+		return
+	}
+
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	s := getPlayerStats(p.name)
+	s.Turns++
+	s.CardsHeldSum += cardsHeld
+	if bluff {
+		s.BluffsAttempted++
+	}
+
+	scheduleStatsSave()
+}
+
+// record a doubt, right is true if the doubted player bluffed
+func recordDoubt(doubter, doubted *player, right bool) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	if doubter.account {
+		s := getPlayerStats(doubter.name)
+		s.DoubtsCalled++
+		if right {
+			s.DoubtsRight++
+		}
+	}
+
+	if doubted.account && right {
+		getPlayerStats(doubted.name).BluffsCaught++
+	}
+
+	if doubter.account || doubted.account {
+		scheduleStatsSave()
+	}
+}
+
+// the stats response message is structured as follows:
+// [player name]\n
+// [games played] [games won]\n
+// [bluffs attempted] [bluffs caught]\n
+// [doubts called] [doubts right]\n
+// [average number of cards held]
+func statsResponse(name string) (string, bool) {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	s, ok := stats[strings.ToLower(name)]
+	if !ok {
+		return "", false
+	}
+
+	resp := s.Name + "\n"
+	resp += strconv.Itoa(s.GamesPlayed) + " " + strconv.Itoa(s.GamesWon) + "\n"
+	resp += strconv.Itoa(s.BluffsAttempted) + " " + strconv.Itoa(s.BluffsCaught) + "\n"
+	resp += strconv.Itoa(s.DoubtsCalled) + " " + strconv.Itoa(s.DoubtsRight) + "\n"
+	resp += fmt.Sprintf("%.1f", s.avgCardsHeld())
+
+	return resp, true
+}
+
+// the leaderboard response message has a line for each player, from the best one, structured as follows:
+// [player name] [games played] [games won]
+//
+// players are ranked by games won, then by the ratio of games won
+func leaderboardResponse() string {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+
+	list := make([]*playerStats, 0, len(stats))
+	for _, s := range stats {
+		if s.GamesPlayed > 0 {
+			list = append(list, s)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.GamesWon != b.GamesWon {
+			return a.GamesWon > b.GamesWon
+		}
+
+		// compare a.GamesWon/a.GamesPlayed with b.GamesWon/b.GamesPlayed
+		if a.GamesWon*b.GamesPlayed != b.GamesWon*a.GamesPlayed {
+			return a.GamesWon*b.GamesPlayed > b.GamesWon*a.GamesPlayed
+		}
+
+		return a.Name < b.Name
+	})
+
+	if len(list) > maxLeaderboardEntries {
+		list = list[:maxLeaderboardEntries]
+	}
+
+	lines := make([]string, len(list))
+	for i, s := range list {
+		lines[i] = s.Name + " " + strconv.Itoa(s.GamesPlayed) + " " + strconv.Itoa(s.GamesWon)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/EdoardoLaGreca/dubito/internal/fileutils"
+)
+
+// directory where the server keeps its persistent data
+var dataDir string = "."
+
+// path of a file inside the data directory
+func dataPath(name string) string {
+	return filepath.Join(dataDir, name)
+}
+
+// loadJSON decodes the content of the file at path into v, a missing file is not an error and leaves v untouched
+func loadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// saveJSON encodes v and writes it to the file at path, a crash never leaves a half-written file
+func saveJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return fileutils.WriteAtomic(path, data, 0600)
+}
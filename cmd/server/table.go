@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/EdoardoLaGreca/dubito/internal/cardutils"
+)
+
+// number of events kept in the history of the table, older events are dropped
+const tableHistoryLength = 200
+
+// a play or a doubt in the history of the table
+type tableEvent struct {
+	doubt  bool
+	player string // player who placed the cards or who doubted
+
+	// plays only
+	count int
+	claim cardutils.Rank
+
+	// doubts only
+	right   bool
+	doubted string // player who placed the doubted cards
+	taken   int    // cards taken by whoever was wrong
+}
+
+// add an event to the history of the table
+// globVarMutex must be held by the caller
+func (r *room) addTableEvent(e tableEvent) {
+	r.tableHistory = append(r.tableHistory, e)
+
+	if len(r.tableHistory) > tableHistoryLength {
+		r.tableHistory = r.tableHistory[len(r.tableHistory)-tableHistoryLength:]
+	}
+}
+
+// the table response message is structured as follows:
+// [number of cards on the table]\n
+// [a "<name> <number of cards held>" pair for each joined player, separated by commas]\n
+// [a line for each play or doubt of the current game, from the oldest one]
+//
+// plays are structured as "p <name> <number of cards> <claimed rank>"
+// doubts are structured as "d <name> <right/wrong> <doubted name> <number of cards taken>"
+// globVarMutex must be held by the caller
+func (r *room) tableResponse() string {
+	lines := make([]string, 0, 2+len(r.tableHistory))
+
+	lines = append(lines, strconv.Itoa(len(r.placedCards)))
+
+	held := make([]string, len(r.players))
+	for i, p := range r.players {
+		held[i] = p.name + " " + strconv.Itoa(p.cards.Len())
+	}
+	lines = append(lines, strings.Join(held, ","))
+
+	for _, e := range r.tableHistory {
+		if !e.doubt {
+			lines = append(lines, "p "+e.player+" "+strconv.Itoa(e.count)+" "+cardutils.RankToString(e.claim))
+		} else if e.right {
+			lines = append(lines, "d "+e.player+" right "+e.doubted+" "+strconv.Itoa(e.taken))
+		} else {
+			lines = append(lines, "d "+e.player+" wrong "+e.doubted+" "+strconv.Itoa(e.taken))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
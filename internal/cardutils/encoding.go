@@ -0,0 +1,114 @@
+package cardutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// symbols of the compact notation, indexed by rank and by suit
+var rankSymbols = [...]string{Ace: "A", Two: "2", Three: "3", Four: "4", Five: "5", Six: "6", Seven: "7", Eight: "8", Nine: "9", Ten: "10", Jack: "J", Queen: "Q", King: "K"}
+var suitSymbols = [...]string{Clubs: "C", Diamonds: "D", Hearts: "H", Spades: "S"}
+
+// Valid returns true if c is one of the 52 cards of a deck
+func (c Card) Valid() bool {
+	return c.Suit >= Clubs && c.Suit <= Spades && c.Rank >= Ace && c.Rank <= King
+}
+
+// Compact returns the compact notation of c, made of the symbols of its rank and of its suit, e.g. "QS" or "10H"
+func (c Card) Compact() string {
+	if !c.Valid() {
+		return ""
+	}
+
+	return rankSymbols[c.Rank] + suitSymbols[c.Suit]
+}
+
+// ParseCard parses a card written either in the compact notation ("QS", "10H") or in the long one ("queen spades").
+// The case and the spaces do not matter and the long form may have an "of" between the rank and the suit.
+func ParseCard(s string) (Card, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 3 && fields[1] == "of" {
+		fields = []string{fields[0], fields[2]}
+	}
+
+	switch len(fields) {
+	case 1:
+		if c, ok := parseCompact(fields[0]); ok {
+			return c, nil
+		}
+	case 2:
+		return CardByName(fields[0] + " " + fields[1])
+	}
+
+	return Card{}, fmt.Errorf("invalid card: %s", strings.TrimSpace(s))
+}
+
+// parse a lowercase card in the compact notation
+func parseCompact(s string) (Card, bool) {
+	if len(s) < 2 {
+		return Card{}, false
+	}
+
+	rankStr, suitStr := s[:len(s)-1], s[len(s)-1:]
+
+	var c Card
+	found := false
+	for r := Ace; r <= King; r++ {
+		if strings.ToLower(rankSymbols[r]) == rankStr {
+			c.Rank, found = r, true
+		}
+	}
+	if !found {
+		return Card{}, false
+	}
+
+	found = false
+	for st := Clubs; st <= Spades; st++ {
+		if strings.ToLower(suitSymbols[st]) == suitStr {
+			c.Suit, found = st, true
+		}
+	}
+
+	return c, found
+}
+
+// MarshalText writes c in the compact notation
+func (c Card) MarshalText() ([]byte, error) {
+	if !c.Valid() {
+		return nil, fmt.Errorf("invalid card: %+v", c)
+	}
+
+	return []byte(c.Compact()), nil
+}
+
+// UnmarshalText reads a card written in any notation accepted by ParseCard
+func (c *Card) UnmarshalText(text []byte) error {
+	parsed, err := ParseCard(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = parsed
+	return nil
+}
+
+// MarshalJSON writes c as a JSON string in the compact notation
+func (c Card) MarshalJSON() ([]byte, error) {
+	text, err := c.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON reads a card from a JSON string in any notation accepted by ParseCard
+func (c *Card) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid card: %s", data)
+	}
+
+	return c.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,104 @@
+package cardutils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// every card of a deck, sorted by suit
+func deck() []Card {
+	cards := make([]Card, 0, 52)
+	for s := Clubs; s <= Spades; s++ {
+		for r := Ace; r <= King; r++ {
+			cards = append(cards, Card{Suit: s, Rank: r})
+		}
+	}
+
+	return cards
+}
+
+func TestCardRoundTrip(t *testing.T) {
+	for _, c := range deck() {
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatalf("%+v: %v", c, err)
+		}
+
+		var back Card
+		if err := back.UnmarshalText(text); err != nil || back != c {
+			t.Errorf("%+v is written as %q, which is read as %+v (%v)", c, text, back, err)
+		}
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("%+v: %v", c, err)
+		}
+
+		back = Card{}
+		if err := json.Unmarshal(data, &back); err != nil || back != c {
+			t.Errorf("%+v is encoded as %s, which is decoded as %+v (%v)", c, data, back, err)
+		}
+
+		if back, err := ParseCard(CardToString(c)); err != nil || back != c {
+			t.Errorf("the long form of %+v is read as %+v (%v)", c, back, err)
+		}
+	}
+}
+
+func TestParseCard(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Card
+	}{
+		{"QS", Card{Suit: Spades, Rank: Queen}},
+		{"10h", Card{Suit: Hearts, Rank: Ten}},
+		{" ac ", Card{Suit: Clubs, Rank: Ace}},
+		{"queen spades", Card{Suit: Spades, Rank: Queen}},
+		{"Seven of  Diamonds", Card{Suit: Diamonds, Rank: Seven}},
+	}
+
+	for _, tt := range tests {
+		if c, err := ParseCard(tt.s); err != nil || c != tt.want {
+			t.Errorf("ParseCard(%q) = %+v, %v, want %+v", tt.s, c, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "Q", "1S", "11H", "QX", "queen", "queen of", "queen spades hearts", "of spades"} {
+		if c, err := ParseCard(s); err == nil {
+			t.Errorf("ParseCard(%q) = %+v, want an error", s, c)
+		}
+	}
+}
+
+func TestMarshalInvalidCard(t *testing.T) {
+	if _, err := json.Marshal(Card{}); err == nil {
+		t.Error("the zero card was encoded")
+	}
+
+	var c Card
+	if err := json.Unmarshal([]byte("42"), &c); err == nil {
+		t.Errorf("a number was decoded as %+v", c)
+	}
+}
+
+func FuzzParseCard(f *testing.F) {
+	f.Add("QS")
+	f.Add("10h")
+	f.Add("queen of spades")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		c, err := ParseCard(s)
+		if err != nil {
+			return
+		}
+
+		if !c.Valid() {
+			t.Fatalf("%q parsed as an invalid card: %+v", s, c)
+		}
+
+		if back, err := ParseCard(c.Compact()); err != nil || back != c {
+			t.Fatalf("%q: %+v is written as %q, which is parsed as %+v (%v)", s, c, c.Compact(), back, err)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package cardutils
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// Hand is a multiset of cards, such as the cards held by a player. The zero value is an empty hand.
+//
+// The cards of a single deck are kept in a bitset, the copies of a card which only games with more decks
+// can have are counted apart. A Hand holds no references, so assigning it copies it.
+type Hand struct {
+	bits  uint64     // bit Suit*13 + Rank-1 is set if the hand holds the card
+	extra [52]uint16 // extra[Suit*13 + Rank-1] is the number of copies of the card beyond the first one
+}
+
+// SortOrder is the order of the cards returned by Hand.Sort
+type SortOrder int
+
+const (
+	// ByRank sorts the cards by rank, from ace to king, and then by suit
+	ByRank SortOrder = iota
+	// BySuit sorts the cards by suit, from clubs to spades, and then by rank
+	BySuit
+)
+
+// return the index of c in the bitset and in the extra copies, false if c is not a valid card
+func cardIndex(c Card) (uint, bool) {
+	if !c.Valid() {
+		return 0, false
+	}
+
+	return uint(c.Suit)*13 + uint(c.Rank) - 1, true
+}
+
+// return the bit of c, 0 if c is not a valid card, so that no hand holds it
+func cardBit(c Card) uint64 {
+	i, ok := cardIndex(c)
+	if !ok {
+		return 0
+	}
+
+	return 1 << i
+}
+
+// NewHand returns a hand which holds cards, the invalid ones are left out
+func NewHand(cards ...Card) Hand {
+	var h Hand
+	for _, c := range cards {
+		h.Add(c)
+	}
+
+	return h
+}
+
+// Add adds cards to the hand, the same card can be added more than once
+// if any of them is not a valid card, the hand is left as it is and an error is returned
+func (h *Hand) Add(cards ...Card) error {
+	for _, c := range cards {
+		if !c.Valid() {
+			return fmt.Errorf("invalid card: %+v", c)
+		}
+	}
+
+	for _, c := range cards {
+		i, _ := cardIndex(c)
+		if h.bits&(1<<i) == 0 {
+			h.bits |= 1 << i
+		} else {
+			h.extra[i]++
+		}
+	}
+
+	return nil
+}
+
+// Remove removes one copy of each of cards from the hand
+// if the hand does not hold all of them, such as when one is not a valid card, it is left as it is and false is returned
+func (h *Hand) Remove(cards ...Card) bool {
+	if !h.ContainsAll(cards...) {
+		return false
+	}
+
+	for _, c := range cards {
+		i, _ := cardIndex(c)
+		if h.extra[i] > 0 {
+			h.extra[i]--
+		} else {
+			h.bits &^= 1 << i
+		}
+	}
+
+	return true
+}
+
+// Count returns how many copies of c the hand holds, 0 if c is not a valid card
+func (h *Hand) Count(c Card) int {
+	i, ok := cardIndex(c)
+	if !ok || h.bits&(1<<i) == 0 {
+		return 0
+	}
+
+	return 1 + int(h.extra[i])
+}
+
+// Contains returns true if the hand holds at least one copy of c, false if c is not a valid card
+func (h *Hand) Contains(c Card) bool {
+	return h.bits&cardBit(c) != 0
+}
+
+// ContainsAll returns true if the hand holds all of cards, a card listed twice must be held twice
+func (h *Hand) ContainsAll(cards ...Card) bool {
+	var wanted uint64
+	for _, c := range cards {
+		b := cardBit(c)
+		if b == 0 || h.bits&b == 0 {
+			return false
+		}
+		if wanted&b == 0 {
+			wanted |= b
+			continue
+		}
+
+		// the card is listed more than once, compare the copies
+		n := 0
+		for _, other := range cards {
+			if other == c {
+				n++
+			}
+		}
+		if n > h.Count(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Len returns the number of cards in the hand
+func (h *Hand) Len() int {
+	n := bits.OnesCount64(h.bits)
+	for _, copies := range h.extra {
+		n += int(copies)
+	}
+
+	return n
+}
+
+// Cards returns the cards of the hand sorted by suit
+func (h *Hand) Cards() []Card {
+	cards := make([]Card, 0, h.Len())
+
+	for s := Clubs; s <= Spades; s++ {
+		for r := Ace; r <= King; r++ {
+			c := Card{Suit: s, Rank: r}
+			for i := h.Count(c); i > 0; i-- {
+				cards = append(cards, c)
+			}
+		}
+	}
+
+	return cards
+}
+
+// Sort returns the cards of the hand in the given order
+func (h *Hand) Sort(order SortOrder) []Card {
+	cards := h.Cards()
+
+	if order == ByRank {
+		sort.SliceStable(cards, func(i, j int) bool {
+			return cards[i].Rank < cards[j].Rank
+		})
+	}
+
+	return cards
+}
+
+// CountByRank returns how many cards of each rank the hand holds, ranks without cards are left out
+func (h *Hand) CountByRank() map[Rank]int {
+	counts := make(map[Rank]int)
+	for _, c := range h.Cards() {
+		counts[c.Rank]++
+	}
+
+	return counts
+}
+
+// GroupByRank returns the cards of the hand grouped by rank and sorted by suit, ranks without cards are left out
+func (h *Hand) GroupByRank() map[Rank][]Card {
+	groups := make(map[Rank][]Card)
+	for _, c := range h.Cards() {
+		groups[c.Rank] = append(groups[c.Rank], c)
+	}
+
+	return groups
+}
+
+// Clone returns a copy of the hand, which is the same as assigning it
+func (h *Hand) Clone() Hand {
+	return *h
+}
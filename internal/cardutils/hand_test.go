@@ -0,0 +1,100 @@
+package cardutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHand(t *testing.T) {
+	aceSpades := Card{Suit: Spades, Rank: Ace}
+	twoClubs := Card{Suit: Clubs, Rank: Two}
+	aceHearts := Card{Suit: Hearts, Rank: Ace}
+
+	var h Hand
+	if h.Len() != 0 || h.Contains(aceSpades) {
+		t.Fatal("the zero hand is not empty")
+	}
+
+	h.Add(aceSpades, twoClubs, aceHearts)
+	if h.Len() != 3 || !h.ContainsAll(twoClubs, aceSpades) {
+		t.Fatalf("unexpected hand: %v", h.Cards())
+	}
+	if h.ContainsAll(aceSpades, aceSpades) {
+		t.Error("a single copy contains a card twice")
+	}
+
+	if got, want := h.Sort(ByRank), []Card{aceHearts, aceSpades, twoClubs}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort(ByRank) = %v, want %v", got, want)
+	}
+	if got, want := h.Sort(BySuit), []Card{twoClubs, aceHearts, aceSpades}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort(BySuit) = %v, want %v", got, want)
+	}
+	if got, want := h.CountByRank(), map[Rank]int{Ace: 2, Two: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CountByRank() = %v, want %v", got, want)
+	}
+	if got, want := h.GroupByRank()[Ace], []Card{aceHearts, aceSpades}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByRank()[Ace] = %v, want %v", got, want)
+	}
+
+	// a failed removal leaves the hand as it is
+	if h.Remove(aceSpades, Card{Suit: Clubs, Rank: King}) || h.Len() != 3 {
+		t.Error("cards removed although the hand does not hold all of them")
+	}
+	if !h.Remove(aceSpades, twoClubs) || h.Len() != 1 || !h.Contains(aceHearts) {
+		t.Errorf("unexpected hand after the removal: %v", h.Cards())
+	}
+}
+
+func TestHandCopies(t *testing.T) {
+	c := Card{Suit: Diamonds, Rank: Queen}
+
+	h := NewHand(c, c, c)
+	if h.Len() != 3 || h.Count(c) != 3 || !h.ContainsAll(c, c, c) || h.ContainsAll(c, c, c, c) {
+		t.Fatalf("unexpected copies: %v", h.Cards())
+	}
+
+	clone := h.Clone()
+	if !h.Remove(c, c) || h.Count(c) != 1 {
+		t.Errorf("unexpected copies after the removal: %v", h.Cards())
+	}
+	if clone.Count(c) != 3 {
+		t.Error("the clone changed with the original hand")
+	}
+
+	if !h.Remove(c) || h.Len() != 0 || h.Contains(c) {
+		t.Errorf("the hand is not empty: %v", h.Cards())
+	}
+}
+
+func TestHandInvalidCards(t *testing.T) {
+	aceSpades := Card{Suit: Spades, Rank: Ace}
+	invalid := Card{Suit: Spades, Rank: King + 1}
+
+	h := NewHand(aceSpades, invalid)
+	if h.Len() != 1 {
+		t.Fatalf("unexpected hand: %v", h.Cards())
+	}
+
+	if err := h.Add(aceSpades, invalid); err == nil || h.Len() != 1 {
+		t.Errorf("invalid card added, err = %v, hand: %v", err, h.Cards())
+	}
+	if h.Contains(invalid) || h.Count(invalid) != 0 || h.ContainsAll(aceSpades, invalid) {
+		t.Error("the hand holds an invalid card")
+	}
+	if h.Remove(aceSpades, invalid) || h.Len() != 1 {
+		t.Error("cards removed along with an invalid one")
+	}
+}
+
+func TestHandAssignment(t *testing.T) {
+	c := Card{Suit: Diamonds, Rank: Queen}
+
+	h := NewHand(c, c)
+	copied := h
+	copied.Add(c)
+	copied.Remove(c, c)
+
+	if h.Count(c) != 2 || copied.Count(c) != 1 {
+		t.Errorf("the copy shares the cards with the original hand: %v and %v", h.Cards(), copied.Cards())
+	}
+}
@@ -0,0 +1,62 @@
+package cardutils
+
+import "github.com/EdoardoLaGreca/dubito/internal/i18n"
+
+// names of the cards shown to the user, which only the client uses
+// the server always uses the English names of RankToString and CardToString, whatever the language of the players
+type cardNames struct {
+	ranks [King + 1]string
+	suits [Spades + 1]string
+	of    string // between the rank and the suit
+}
+
+var localCardNames = map[i18n.Lang]cardNames{
+	i18n.English: {
+		ranks: [...]string{Ace: "ace", Two: "two", Three: "three", Four: "four", Five: "five", Six: "six", Seven: "seven", Eight: "eight", Nine: "nine", Ten: "ten", Jack: "jack", Queen: "queen", King: "king"},
+		suits: [...]string{Clubs: "clubs", Diamonds: "diamonds", Hearts: "hearts", Spades: "spades"},
+		of:    "of",
+	},
+	i18n.Italian: {
+		ranks: [...]string{Ace: "asso", Two: "due", Three: "tre", Four: "quattro", Five: "cinque", Six: "sei", Seven: "sette", Eight: "otto", Nine: "nove", Ten: "dieci", Jack: "fante", Queen: "donna", King: "re"},
+		suits: [...]string{Clubs: "fiori", Diamonds: "quadri", Hearts: "cuori", Spades: "picche"},
+		of:    "di",
+	},
+}
+
+// return the names of lang, or the English ones if lang has none
+func namesOf(lang i18n.Lang) cardNames {
+	if names, ok := localCardNames[lang]; ok {
+		return names
+	}
+
+	return localCardNames[i18n.English]
+}
+
+// LocalRankName returns the name of r in lang, e.g. "queen" or "donna"
+func LocalRankName(r Rank, lang i18n.Lang) string {
+	if r < Ace || r > King {
+		return ""
+	}
+
+	return namesOf(lang).ranks[r]
+}
+
+// LocalCardName returns the name of c in lang, e.g. "queen of spades" or "donna di picche"
+func LocalCardName(c Card, lang i18n.Lang) string {
+	if !c.Valid() {
+		return ""
+	}
+
+	names := namesOf(lang)
+	return names.ranks[c.Rank] + " " + names.of + " " + names.suits[c.Suit]
+}
+
+// LocalCardNames returns the names of cards in lang
+func LocalCardNames(cards []Card, lang i18n.Lang) []string {
+	names := make([]string, len(cards))
+	for i, c := range cards {
+		names[i] = LocalCardName(c, lang)
+	}
+
+	return names
+}
@@ -0,0 +1,31 @@
+package cardutils
+
+import (
+	"testing"
+
+	"github.com/EdoardoLaGreca/dubito/internal/i18n"
+)
+
+func TestLocalCardName(t *testing.T) {
+	if got := LocalCardName(Card{Suit: Spades, Rank: Queen}, i18n.Italian); got != "donna di picche" {
+		t.Errorf("the queen of spades is called %q in Italian", got)
+	}
+
+	for _, l := range i18n.Langs {
+		seen := make(map[string]bool)
+		for _, c := range deck() {
+			name := LocalCardName(c, l)
+			if name == "" || seen[name] {
+				t.Errorf("%s: %+v has a missing or repeated name: %q", l, c, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	// the English names can be read back, the wire format is not affected by the language
+	for _, c := range deck() {
+		if back, err := ParseCard(LocalCardName(c, i18n.English)); err != nil || back != c {
+			t.Errorf("%q is read as %+v (%v)", LocalCardName(c, i18n.English), back, err)
+		}
+	}
+}
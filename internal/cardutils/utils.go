@@ -79,6 +79,9 @@ func RankByName(name string) (Rank, error) {
 // e.g. "five clubs" or "queen spades"
 func CardByName(name string) (Card, error) {
 	nameSp := strings.Fields(name)
+	if len(nameSp) != 2 {
+		return Card{}, fmt.Errorf("invalid card: " + name)
+	}
 
 	rankStr := nameSp[0]
 	suitStr := nameSp[1]
@@ -169,3 +172,12 @@ func CardsToString(cards []Card) []string {
 
 	return cardsStr
 }
+
+// NextRank returns the rank which follows r, the king is followed by the ace
+func NextRank(r Rank) Rank {
+	if r >= King {
+		return Ace
+	}
+
+	return r + 1
+}
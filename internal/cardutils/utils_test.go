@@ -0,0 +1,75 @@
+package cardutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNextRank(t *testing.T) {
+	for r := Ace; r < King; r++ {
+		if next := NextRank(r); next != r+1 {
+			t.Errorf("the rank after %s is %s", RankToString(r), RankToString(next))
+		}
+	}
+
+	// the ranks go around, the king is followed by the ace
+	if next := NextRank(King); next != Ace {
+		t.Errorf("the rank after king is %s", RankToString(next))
+	}
+}
+
+func FuzzCardByName(f *testing.F) {
+	f.Add("five clubs")
+	f.Add("queen spades")
+	f.Add(" ace\thearts ")
+	f.Add("king diamonds extra")
+	f.Add("ace")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, name string) {
+		c, err := CardByName(name)
+		if err != nil {
+			return
+		}
+
+		if c.Rank < Ace || c.Rank > King || c.Suit < Clubs || c.Suit > Spades {
+			t.Fatalf("%q parsed as an invalid card: %+v", name, c)
+		}
+
+		// the name of a card is parsed back into the same card
+		back, err := CardByName(CardToString(c))
+		if err != nil || back != c {
+			t.Fatalf("%q: %+v is written as %q, which is parsed as %+v (%v)", name, c, CardToString(c), back, err)
+		}
+
+		if CardToString(c) != strings.Join(strings.Fields(name), " ") {
+			t.Fatalf("%q is written back as %q", name, CardToString(c))
+		}
+	})
+}
+
+func FuzzRankByName(f *testing.F) {
+	f.Add("ace")
+	f.Add("ten")
+	f.Add("Ace")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, name string) {
+		r, err := RankByName(name)
+		if err != nil {
+			return
+		}
+
+		if r < Ace || r > King {
+			t.Fatalf("%q parsed as an invalid rank: %d", name, r)
+		}
+
+		if RankToString(r) != name {
+			t.Fatalf("%q is written back as %q", name, RankToString(r))
+		}
+
+		if n := NextRank(r); n < Ace || n > King {
+			t.Fatalf("the rank after %q is invalid: %d", name, n)
+		}
+	})
+}
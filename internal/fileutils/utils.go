@@ -0,0 +1,18 @@
+package fileutils
+
+import "os"
+
+// WriteAtomic writes data to the file at path, creating it with perm if it does not exist.
+// The data is first written to a temporary file which then replaces the old one, so that a crash never
+// leaves a half-written file.
+func WriteAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+
+	err := os.WriteFile(tmpPath, data, perm)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,28 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+
+	for _, content := range []string{"first", "second"} {
+		err := WriteAtomic(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Fatalf("the file contains %q (%v), want %q", data, err, content)
+		}
+	}
+
+	// the temporary file does not outlive the write
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("the temporary file was left behind: %v", err)
+	}
+}
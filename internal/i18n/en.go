@@ -0,0 +1,164 @@
+package i18n
+
+// English messages, every key must also be in the other catalogs
+var english = catalog{
+	// main menu
+	"menu.newGame":      "New game",
+	"menu.leaderboard":  "Leaderboard",
+	"menu.settings":     "Settings",
+	"menu.quickConnect": "Quick connect",
+	"menu.profile":      "%s (%s on %s:%d)",
+
+	// buttons and dialogs used in more places
+	"common.back":   "Back",
+	"common.cancel": "Cancel",
+	"common.save":   "Save",
+	"common.leave":  "Leave",
+
+	// settings
+	"settings.username":      "Username",
+	"settings.password":      "Password (leave empty to play as a guest)",
+	"settings.address":       "Server address",
+	"settings.port":          "Server port",
+	"settings.invalidPort":   "invalid port number",
+	"settings.deckStyle":     "Deck style",
+	"settings.style":         "Style %d",
+	"settings.language":      "Language",
+	"settings.createAccount": "Create account",
+	"settings.discover":      "Servers on your network",
+	"settings.profile":       "Profile",
+	"settings.chooseProfile": "Choose a saved server",
+	"settings.saveProfile":   "Save as profile",
+	"settings.deleteProfile": "Delete profile",
+	"settings.profileName":   "Profile name",
+
+	// servers on the local network
+	"discovery.none":   "No server found.",
+	"discovery.server": "%s (%s:%d), %d rooms, %d free seats",
+
+	// accounts
+	"account.choosePassword": "choose a password first",
+	"account.created":        "Account created",
+	"account.createdText":    "You can now play as %s.",
+
+	// quality of the connection
+	"connection.label":         "Connection: %s",
+	"connection.connecting":    "connecting...",
+	"connection.lost":          "lost",
+	"connection.unstable":      "unstable (no answer for %d seconds)",
+	"connection.good":          "good (%d ms)",
+	"connection.fair":          "fair (%d ms)",
+	"connection.poor":          "poor (%d ms)",
+	"connection.closed":        "connection lost",
+	"connection.notResponding": "the server is not responding",
+	"connection.badResponse":   "the server sent an invalid response to \"%s\"",
+	"connection.badLine":       "the server sent an invalid line: %q",
+
+	// list of rooms
+	"rooms.title":            "Rooms",
+	"rooms.entry":            "Room %s, %d/%d players",
+	"rooms.host":             ", host: %s",
+	"rooms.playing":          " (playing)",
+	"rooms.ranked":           " (ranked)",
+	"rooms.refresh":          "Refresh",
+	"rooms.create":           "Create room",
+	"rooms.createPrivate":    "Create private room",
+	"rooms.passwordOptional": "Password (optional)",
+	"rooms.createConfirm":    "Create",
+	"rooms.joinPrivate":      "Join private room",
+	"rooms.inviteOrRoom":     "Invite code or room number",
+	"rooms.password":         "Password",
+	"rooms.joinConfirm":      "Join",
+
+	// waiting room
+	"waiting.ready":          "Ready",
+	"waiting.changeSettings": "Change settings",
+	"waiting.kick":           "Kick a player",
+	"waiting.start":          "Start",
+	"waiting.room":           "Room %s",
+	"waiting.private":        " (private, invite code: %s)",
+	"waiting.players":        "%d joined, %d to %d players can play",
+	"waiting.variant":        "Variant: %s",
+	"waiting.turnTime":       "Turn time: %d seconds",
+	"waiting.noTurnLimit":    "Turn time: no limit",
+	"waiting.ranked":         "Ranked: the ratings change at the end of the game",
+	"waiting.unranked":       "Not ranked",
+	"waiting.host":           "%s (host)",
+	"waiting.isReady":        "%s (ready)",
+	"waiting.notReady":       "%s (not ready)",
+
+	// variants of the game, the keys end with the name used by the server
+	"variant.classic":   "classic",
+	"variant.placement": "placement",
+
+	// settings of the room and moderation
+	"roomSettings.title":      "Room settings",
+	"roomSettings.minPlayers": "Minimum players",
+	"roomSettings.maxPlayers": "Maximum players",
+	"roomSettings.variant":    "Variant",
+	"roomSettings.turnTime":   "Turn time (seconds, 0 for no limit)",
+	"roomSettings.ranked":     "Ranked",
+	"kick.player":             "Player",
+	"kick.confirm":            "Kick",
+
+	// game
+	"game.title":           "Dubito | in game as %s",
+	"game.noneSelected":    "You selected 0 cards",
+	"game.selected":        "You selected %d cards: %s",
+	"game.tooManySelected": "you selected 4 cards already",
+	"game.place":           "Place cards",
+	"game.dubito":          "Dubito!",
+	"game.right":           "Correct!",
+	"game.rightText":       "You doubted well! :)",
+	"game.wrong":           "Wrong!",
+	"game.wrongText":       "You doubted wrong... :(",
+	"game.shutdown":        "The server is shutting down",
+	"game.shutdownText":    "The game will be ended in %d seconds if it is still running.",
+	"game.finished":        "You finished!",
+	"game.finishedText":    "You finished in position %d, wait for the others to finish.",
+	"game.won":             "You won!",
+	"game.wonText":         "Congrats, you won this game! :)",
+	"game.lost":            "You lost...",
+	"game.lostText":        "You lost this game. :(",
+
+	// placement of the cards and the claim
+	"place.cards":    "Cards",
+	"place.claim":    "Claimed rank",
+	"place.free":     "You start the pile, you can claim any rank",
+	"place.required": "You have to claim %s",
+	"place.bluff":    "%d of the %d cards do not match %s, this is a bluff",
+	"place.confirm":  "Place",
+
+	// hand of the player
+	"hand.sort":      "Sort by:",
+	"hand.byRank":    "rank",
+	"hand.bySuit":    "suit",
+	"hand.selectAll": "Select all:",
+	"hand.rank":      "%s (%d)",
+	"hand.claim":     "You claim %d × %s, %d of them bluffed (you hold %d)",
+
+	// table of the game
+	"table.history":    "History of the game",
+	"table.pile":       "%d cards on the table",
+	"table.newPile":    "The table is empty, the next player can claim any rank",
+	"table.lastClaim":  "%s: %d × %s",
+	"table.held":       "%s, %d cards",
+	"table.play":       "%s placed %d × %s",
+	"table.doubtRight": "%s doubted %s and was right, %d cards go to the bluffer",
+	"table.doubtWrong": "%s doubted %s and was wrong, %d cards go to the doubter",
+
+	// chat
+	"chat.placeholder": "Write a message",
+	"chat.send":        "Send",
+	"chat.usage":       "usage: %s <name>",
+
+	// leaderboard and statistics
+	"leaderboard.title":    "Leaderboard (click on a player to see their statistics)",
+	"leaderboard.empty":    "Nobody played a game yet",
+	"leaderboard.entry":    "%d. %s, %d won out of %d",
+	"stats.won":            "Games won: %d out of %d",
+	"stats.bluffs":         "Bluffs caught: %d out of %d",
+	"stats.doubts":         "Doubts right: %d out of %d",
+	"stats.doubtsAccuracy": "Doubts right: %d out of %d (%.0f%%)",
+	"stats.avgCards":       "Average cards held: %.1f",
+}
@@ -0,0 +1,115 @@
+// Package i18n translates the text shown to the user. The messages are looked up by key in the catalog of the
+// current language, which falls back to English, and may contain fmt verbs filled in by the arguments of T.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Lang is a language, identified by its ISO 639-1 code
+type Lang string
+
+const (
+	English Lang = "en"
+	Italian Lang = "it"
+)
+
+// Langs lists the supported languages, English is the fallback one
+var Langs = []Lang{English, Italian}
+
+// a catalog maps the key of each message to its translation
+type catalog map[string]string
+
+var catalogs = map[Lang]catalog{
+	English: english,
+	Italian: italian,
+}
+
+var current = English
+var currentMutex sync.RWMutex
+
+// Name returns the name of the language in the language itself, to be shown in the language selector
+func (l Lang) Name() string {
+	switch l {
+	case English:
+		return "English"
+	case Italian:
+		return "Italiano"
+	}
+
+	return string(l)
+}
+
+// ParseLang returns the language of a code such as "it" or a locale such as "it_IT.UTF-8"
+func ParseLang(s string) (Lang, error) {
+	code, _, _ := strings.Cut(strings.ToLower(s), "_")
+	code, _, _ = strings.Cut(code, "-")
+	code, _, _ = strings.Cut(code, ".")
+
+	for _, l := range Langs {
+		if string(l) == code {
+			return l, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported language: %s", s)
+}
+
+// DetectLang returns the language of the environment, taken from LC_ALL, LC_MESSAGES or LANG, or English
+func DetectLang() Lang {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if l, err := ParseLang(value); err == nil {
+				return l
+			}
+			// the first variable which is set wins, even if its language is not supported
+			break
+		}
+	}
+
+	return English
+}
+
+// SetLang changes the language of the messages returned by T
+func SetLang(l Lang) {
+	currentMutex.Lock()
+	defer currentMutex.Unlock()
+
+	current = l
+}
+
+// CurrentLang returns the language of the messages returned by T
+func CurrentLang() Lang {
+	currentMutex.RLock()
+	defer currentMutex.RUnlock()
+
+	return current
+}
+
+// Lookup returns the message of key in the current language, or in English if it is not translated
+func Lookup(key string) (string, bool) {
+	if msg, ok := catalogs[CurrentLang()][key]; ok {
+		return msg, true
+	}
+
+	msg, ok := english[key]
+	return msg, ok
+}
+
+// T returns the message of key in the current language, formatted with args
+// unknown keys are returned as they are, so that a missing message is visible but does not break the UI
+func T(key string, args ...any) string {
+	msg, ok := Lookup(key)
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,91 @@
+package i18n
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// fmt verbs, such as %d or %.1f, and the escaped %
+var verbRegexp = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, l := range Langs {
+		c := catalogs[l]
+		if c == nil {
+			t.Fatalf("%s has no catalog", l)
+		}
+
+		for key, msg := range english {
+			translation, ok := c[key]
+			if !ok {
+				t.Errorf("%s: %q is not translated", l, key)
+				continue
+			}
+
+			// the arguments are the same in every language, so the verbs must be the same too
+			if want, got := verbRegexp.FindAllString(msg, -1), verbRegexp.FindAllString(translation, -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %q has the verbs %v, want %v", l, key, got, want)
+			}
+		}
+
+		for key := range c {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s: %q is not an English message", l, key)
+			}
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLang(CurrentLang())
+
+	SetLang(Italian)
+	if got := T("settings.style", 3); got != "Stile 3" {
+		t.Errorf("T(settings.style) = %q", got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("T(no.such.key) = %q", got)
+	}
+
+	SetLang(English)
+	if got := T("menu.newGame"); got != "New game" {
+		t.Errorf("T(menu.newGame) = %q", got)
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Lang
+	}{
+		{"it", Italian},
+		{"it_IT.UTF-8", Italian},
+		{"en-GB", English},
+		{"EN", English},
+	}
+
+	for _, tt := range tests {
+		if l, err := ParseLang(tt.s); err != nil || l != tt.want {
+			t.Errorf("ParseLang(%q) = %q, %v, want %q", tt.s, l, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "C", "fr_FR.UTF-8"} {
+		if l, err := ParseLang(s); err == nil {
+			t.Errorf("ParseLang(%q) = %q, want an error", s, l)
+		}
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "it_IT.UTF-8")
+	if l := DetectLang(); l != Italian {
+		t.Errorf("DetectLang() = %q with LANG=it_IT.UTF-8", l)
+	}
+
+	t.Setenv("LC_ALL", "C")
+	if l := DetectLang(); l != English {
+		t.Errorf("DetectLang() = %q with LC_ALL=C", l)
+	}
+}
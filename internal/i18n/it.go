@@ -0,0 +1,164 @@
+package i18n
+
+// Italian messages
+var italian = catalog{
+	// main menu
+	"menu.newGame":      "Nuova partita",
+	"menu.leaderboard":  "Classifica",
+	"menu.settings":     "Impostazioni",
+	"menu.quickConnect": "Connessione rapida",
+	"menu.profile":      "%s (%s su %s:%d)",
+
+	// buttons and dialogs used in more places
+	"common.back":   "Indietro",
+	"common.cancel": "Annulla",
+	"common.save":   "Salva",
+	"common.leave":  "Esci",
+
+	// settings
+	"settings.username":      "Nome utente",
+	"settings.password":      "Password (lascia vuoto per giocare come ospite)",
+	"settings.address":       "Indirizzo del server",
+	"settings.port":          "Porta del server",
+	"settings.invalidPort":   "numero di porta non valido",
+	"settings.deckStyle":     "Stile del mazzo",
+	"settings.style":         "Stile %d",
+	"settings.language":      "Lingua",
+	"settings.createAccount": "Crea account",
+	"settings.discover":      "Server nella tua rete",
+	"settings.profile":       "Profilo",
+	"settings.chooseProfile": "Scegli un server salvato",
+	"settings.saveProfile":   "Salva come profilo",
+	"settings.deleteProfile": "Elimina profilo",
+	"settings.profileName":   "Nome del profilo",
+
+	// servers on the local network
+	"discovery.none":   "Nessun server trovato.",
+	"discovery.server": "%s (%s:%d), %d stanze, %d posti liberi",
+
+	// accounts
+	"account.choosePassword": "scegli prima una password",
+	"account.created":        "Account creato",
+	"account.createdText":    "Ora puoi giocare come %s.",
+
+	// quality of the connection
+	"connection.label":         "Connessione: %s",
+	"connection.connecting":    "connessione in corso...",
+	"connection.lost":          "persa",
+	"connection.unstable":      "instabile (nessuna risposta da %d secondi)",
+	"connection.good":          "buona (%d ms)",
+	"connection.fair":          "discreta (%d ms)",
+	"connection.poor":          "scarsa (%d ms)",
+	"connection.closed":        "connessione persa",
+	"connection.notResponding": "il server non risponde",
+	"connection.badResponse":   "il server ha inviato una risposta non valida a \"%s\"",
+	"connection.badLine":       "il server ha inviato una riga non valida: %q",
+
+	// list of rooms
+	"rooms.title":            "Stanze",
+	"rooms.entry":            "Stanza %s, %d/%d giocatori",
+	"rooms.host":             ", organizzatore: %s",
+	"rooms.playing":          " (in gioco)",
+	"rooms.ranked":           " (classificata)",
+	"rooms.refresh":          "Aggiorna",
+	"rooms.create":           "Crea stanza",
+	"rooms.createPrivate":    "Crea stanza privata",
+	"rooms.passwordOptional": "Password (facoltativa)",
+	"rooms.createConfirm":    "Crea",
+	"rooms.joinPrivate":      "Entra in una stanza privata",
+	"rooms.inviteOrRoom":     "Codice di invito o numero della stanza",
+	"rooms.password":         "Password",
+	"rooms.joinConfirm":      "Entra",
+
+	// waiting room
+	"waiting.ready":          "Pronto",
+	"waiting.changeSettings": "Cambia impostazioni",
+	"waiting.kick":           "Espelli un giocatore",
+	"waiting.start":          "Inizia",
+	"waiting.room":           "Stanza %s",
+	"waiting.private":        " (privata, codice di invito: %s)",
+	"waiting.players":        "%d giocatori presenti, possono giocare da %d a %d giocatori",
+	"waiting.variant":        "Variante: %s",
+	"waiting.turnTime":       "Tempo per turno: %d secondi",
+	"waiting.noTurnLimit":    "Tempo per turno: nessun limite",
+	"waiting.ranked":         "Classificata: i punteggi cambiano alla fine della partita",
+	"waiting.unranked":       "Non classificata",
+	"waiting.host":           "%s (organizzatore)",
+	"waiting.isReady":        "%s (pronto)",
+	"waiting.notReady":       "%s (non pronto)",
+
+	// variants of the game, the keys end with the name used by the server
+	"variant.classic":   "classica",
+	"variant.placement": "a piazzamenti",
+
+	// settings of the room and moderation
+	"roomSettings.title":      "Impostazioni della stanza",
+	"roomSettings.minPlayers": "Giocatori minimi",
+	"roomSettings.maxPlayers": "Giocatori massimi",
+	"roomSettings.variant":    "Variante",
+	"roomSettings.turnTime":   "Tempo per turno (secondi, 0 per nessun limite)",
+	"roomSettings.ranked":     "Classificata",
+	"kick.player":             "Giocatore",
+	"kick.confirm":            "Espelli",
+
+	// game
+	"game.title":           "Dubito | in gioco come %s",
+	"game.noneSelected":    "Non hai selezionato nessuna carta",
+	"game.selected":        "Hai selezionato %d carte: %s",
+	"game.tooManySelected": "hai già selezionato 4 carte",
+	"game.place":           "Metti le carte",
+	"game.dubito":          "Dubito!",
+	"game.right":           "Giusto!",
+	"game.rightText":       "Hai dubitato bene! :)",
+	"game.wrong":           "Sbagliato!",
+	"game.wrongText":       "Hai dubitato male... :(",
+	"game.shutdown":        "Il server si sta spegnendo",
+	"game.shutdownText":    "La partita sarà terminata tra %d secondi se è ancora in corso.",
+	"game.finished":        "Hai finito!",
+	"game.finishedText":    "Hai finito in posizione %d, aspetta che finiscano gli altri.",
+	"game.won":             "Hai vinto!",
+	"game.wonText":         "Complimenti, hai vinto questa partita! :)",
+	"game.lost":            "Hai perso...",
+	"game.lostText":        "Hai perso questa partita. :(",
+
+	// placement of the cards and the claim
+	"place.cards":    "Carte",
+	"place.claim":    "Valore dichiarato",
+	"place.free":     "Inizi tu il mazzo, puoi dichiarare qualsiasi valore",
+	"place.required": "Devi dichiarare %s",
+	"place.bluff":    "%d carte su %d non corrispondono a %s, è un bluff",
+	"place.confirm":  "Metti",
+
+	// hand of the player
+	"hand.sort":      "Ordina per:",
+	"hand.byRank":    "valore",
+	"hand.bySuit":    "seme",
+	"hand.selectAll": "Seleziona tutte:",
+	"hand.rank":      "%s (%d)",
+	"hand.claim":     "Dichiari %d × %s, %d delle quali false (ne hai %d)",
+
+	// table of the game
+	"table.history":    "Storia della partita",
+	"table.pile":       "%d carte sul tavolo",
+	"table.newPile":    "Il tavolo è vuoto, il prossimo giocatore può dichiarare qualsiasi valore",
+	"table.lastClaim":  "%s: %d × %s",
+	"table.held":       "%s, %d carte",
+	"table.play":       "%s ha messo %d × %s",
+	"table.doubtRight": "%s ha dubitato di %s e aveva ragione, %d carte vanno a chi ha bluffato",
+	"table.doubtWrong": "%s ha dubitato di %s e aveva torto, %d carte vanno a chi ha dubitato",
+
+	// chat
+	"chat.placeholder": "Scrivi un messaggio",
+	"chat.send":        "Invia",
+	"chat.usage":       "uso: %s <nome>",
+
+	// leaderboard and statistics
+	"leaderboard.title":    "Classifica (clicca su un giocatore per vedere le sue statistiche)",
+	"leaderboard.empty":    "Nessuno ha ancora giocato una partita",
+	"leaderboard.entry":    "%d. %s, %d vinte su %d",
+	"stats.won":            "Partite vinte: %d su %d",
+	"stats.bluffs":         "Bluff scoperti: %d su %d",
+	"stats.doubts":         "Dubbi giusti: %d su %d",
+	"stats.doubtsAccuracy": "Dubbi giusti: %d su %d (%.0f%%)",
+	"stats.avgCards":       "Media delle carte in mano: %.1f",
+}
@@ -0,0 +1,134 @@
+package netutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiscoveryPort is the UDP port where servers listen for discovery queries.
+const DiscoveryPort = 9877
+
+// message broadcast by clients looking for servers
+const discoveryQuery = "dubito?"
+
+// ServerInfo describes a server found on the local network.
+type ServerInfo struct {
+	Name      string
+	Address   string
+	Port      uint16 // TCP port of the game
+	Rooms     int
+	FreeSeats int // seats left in the rooms which did not start a game yet
+}
+
+// the announcement is structured as follows:
+// dubito [port] [number of rooms] [free seats] [server name]
+//
+// the name is the last field because it may contain spaces
+func formatAnnouncement(info ServerInfo) string {
+	return "dubito " + strconv.Itoa(int(info.Port)) + " " + strconv.Itoa(info.Rooms) + " " + strconv.Itoa(info.FreeSeats) + " " + info.Name
+}
+
+func parseAnnouncement(msg string) (ServerInfo, error) {
+	fields := strings.SplitN(msg, " ", 5)
+	if len(fields) != 5 || fields[0] != "dubito" {
+		return ServerInfo{}, fmt.Errorf("invalid announcement")
+	}
+
+	port, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	rooms, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	freeSeats, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return ServerInfo{}, err
+	}
+
+	return ServerInfo{Name: fields[4], Port: uint16(port), Rooms: rooms, FreeSeats: freeSeats}, nil
+}
+
+// ServeDiscovery answers the discovery queries of the clients on the local network until ctx is done, in which case it returns nil.
+// status is called for each query and returns the current state of the server.
+func ServeDiscovery(ctx context.Context, status func() ServerInfo) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: DiscoveryPort})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 64)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if string(buf[:n]) != discoveryQuery {
+			continue
+		}
+
+		// a failed answer only affects that client
+		conn.WriteToUDP([]byte(formatAnnouncement(status())), addr)
+	}
+}
+
+// Discover broadcasts a discovery query on the local network and returns the servers which answered within timeout.
+func Discover(timeout time.Duration) ([]ServerInfo, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	_, err = conn.WriteToUDP([]byte(discoveryQuery), &net.UDPAddr{IP: net.IPv4bcast, Port: DiscoveryPort})
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]ServerInfo, 0)
+	found := make(map[string]bool)
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return servers, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		info, err := parseAnnouncement(string(buf[:n]))
+		if err != nil {
+			continue
+		}
+		info.Address = addr.IP.String()
+
+		// a server may answer more than once if it has several network interfaces
+		key := info.Address + ":" + strconv.Itoa(int(info.Port))
+		if !found[key] {
+			found[key] = true
+			servers = append(servers, info)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package netutils
 
 type NetQueueItem struct {
 	content []byte
+	err     error // not nil if the item stands for a message which could not be read
 }
 
 type NetQueue struct {
@@ -27,6 +28,15 @@ func NewItem(content []byte) *NetQueueItem {
 	return nqi
 }
 
+// newErrItem creates an item standing for a message which could not be read because of err.
+func newErrItem(err error) *NetQueueItem {
+	nqi := new(NetQueueItem)
+
+	nqi.err = err
+
+	return nqi
+}
+
 func (nqi NetQueueItem) Content() []byte {
 	return nqi.content
 }
@@ -1,10 +1,13 @@
 package netutils
 
 import (
+	"errors"
 	"net"
 )
 
-var nq NetQueue = NewQueue()
+// ErrMsgTooLong is returned by MsgReader.Recv in place of a message longer than the maximum length.
+// The message is discarded and the following ones can still be read.
+var ErrMsgTooLong = errors.New("the message is too long")
 
 func SendMsg(conn net.Conn, msg string) error {
 	_, err := conn.Write([]byte(msg + "\000"))
@@ -12,54 +15,65 @@ func SendMsg(conn net.Conn, msg string) error {
 	return err
 }
 
-// RecvMsg reads the connection, stores all the incoming messages in the queue as
-// successive items and returns the next item in the queue as a string
-func RecvMsg(conn net.Conn) (string, error) {
-	if nq.IsEmpty() {
-		msg := make([]byte, 0)
-		buf := make([]byte, 1024)
-
-		for {
-			n, err := conn.Read(buf)
-			if err != nil {
-				return "", err
-			}
+// MsgReader reads the messages of a connection, each connection needs its own reader.
+type MsgReader struct {
+	conn   net.Conn
+	maxLen int // 0 if there is no limit
 
-			// beginning of a new message
-			start := 0
+	// the complete messages which have not been returned yet
+	queue NetQueue
 
-			// read all incoming messages
-			for i := 0; i < n; i++ {
-				if buf[i] == 0x00 {
-					// append the new message to msg
-					msg = append(msg, buf[start:i]...)
+	// the beginning of the next message
+	partial []byte
 
-					// make a copy of the message
-					msgCopy := make([]byte, len(msg))
-					copy(msgCopy, msg)
+	// true if the message being read is too long, its bytes are discarded until the end of the message
+	discarding bool
+}
 
-					// add the message copy to the queue
-					nqi := NewItem(msgCopy)
-					nq.AddItem(nqi)
+// NewMsgReader creates a reader for conn which refuses the messages longer than maxLen bytes, 0 for no limit.
+func NewMsgReader(conn net.Conn, maxLen int) *MsgReader {
+	return &MsgReader{conn: conn, maxLen: maxLen, queue: NewQueue()}
+}
 
-					// set/reset the variables
-					msg = make([]byte, 0)
-					start = i + 1
-				} else if i == n-1 {
-					// append all the buffer from start
-					msg = append(msg, buf[start:]...)
+// Recv reads the connection, stores all the incoming messages in the queue as
+// successive items and returns the next item in the queue as a string
+func (mr *MsgReader) Recv() (string, error) {
+	buf := make([]byte, 1024)
+
+	for mr.queue.IsEmpty() {
+		n, err := mr.conn.Read(buf)
 
-					// reset start
-					start = 0
+		// the data read before the error is still valid
+		for _, b := range buf[:n] {
+			if b == 0x00 {
+				if mr.discarding {
+					mr.queue.AddItem(newErrItem(ErrMsgTooLong))
+				} else {
+					mr.queue.AddItem(NewItem(mr.partial))
 				}
-			}
 
-			// we reached the end of available data
-			if n < len(buf) {
-				break
+				// a new slice, the queued item keeps the old one
+				mr.partial = make([]byte, 0)
+				mr.discarding = false
+			} else if !mr.discarding {
+				mr.partial = append(mr.partial, b)
+
+				if mr.maxLen > 0 && len(mr.partial) > mr.maxLen {
+					mr.partial = make([]byte, 0)
+					mr.discarding = true
+				}
 			}
 		}
+
+		if err != nil && mr.queue.IsEmpty() {
+			return "", err
+		}
+	}
+
+	item := mr.queue.Next()
+	if item.err != nil {
+		return "", item.err
 	}
 
-	return string(nq.Next().Content()), nil
+	return string(item.Content()), nil
 }
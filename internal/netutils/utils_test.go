@@ -0,0 +1,86 @@
+package netutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// a message the reader is expected to return, err is set if the message is refused
+type wantMsg struct {
+	msg string
+	err error
+}
+
+// split data into the messages the reader must return, the bytes after the last terminator are not a message
+func splitMessages(data []byte, maxLen int) []wantMsg {
+	parts := bytes.Split(data, []byte{0})
+	parts = parts[:len(parts)-1]
+
+	msgs := make([]wantMsg, len(parts))
+	for i, p := range parts {
+		if maxLen > 0 && len(p) > maxLen {
+			msgs[i] = wantMsg{err: ErrMsgTooLong}
+		} else {
+			msgs[i] = wantMsg{msg: string(p)}
+		}
+	}
+
+	return msgs
+}
+
+// write data to a connection in chunks of chunkSize bytes and check the messages read from the other end
+func checkFraming(t *testing.T, data []byte, chunkSize, maxLen int) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		defer client.Close()
+
+		for len(data) > 0 {
+			n := min(chunkSize, len(data))
+			if _, err := client.Write(data[:n]); err != nil {
+				return
+			}
+			data = data[n:]
+		}
+	}()
+
+	want := splitMessages(data, maxLen)
+	reader := NewMsgReader(server, maxLen)
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for i, w := range want {
+		msg, err := reader.Recv()
+		if !errors.Is(err, w.err) || msg != w.msg {
+			t.Fatalf("message %d: got %q (%v), want %q (%v)", i, msg, err, w.msg, w.err)
+		}
+	}
+
+	// the data after the last terminator is dropped when the connection closes
+	msg, err := reader.Recv()
+	if err != io.EOF {
+		t.Fatalf("got %q (%v) after the last message, want EOF", msg, err)
+	}
+}
+
+func FuzzMsgReader(f *testing.F) {
+	f.Add([]byte("get update\000"), uint16(1), uint16(0))
+	f.Add([]byte("join alice\000ready y\000start\000"), uint16(1024), uint16(0))
+	f.Add([]byte("\000\000partial"), uint16(3), uint16(4))
+	f.Add([]byte("too long\000ok\000"), uint16(5), uint16(4))
+
+	// messages longer than the read buffer of the reader
+	f.Add(append(bytes.Repeat([]byte("a"), 1500), 0, 'b', 0), uint16(1024), uint16(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, chunkSize uint16, maxLen uint16) {
+		if chunkSize == 0 {
+			chunkSize = 1
+		}
+
+		checkFraming(t, data, int(chunkSize), int(maxLen))
+	})
+}